@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/langorou/langorou/pkg/analysis"
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+type analyzeCommand struct {
+	timeout   time.Duration
+	depth     uint
+	threshold float64
+	player    int
+	format    string
+	engine    string
+	explain   bool
+}
+
+func (c *analyzeCommand) flags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.timeout, "timeout", 5*time.Second, "time budget to search each position")
+	fs.UintVar(&c.depth, "depth", 0, "search each position at this depth instead of during -timeout, for reproducible reports")
+	fs.Float64Var(&c.threshold, "threshold", 5, "loss of expected value above which a move is flagged as a blunder")
+	fs.IntVar(&c.player, "player", 0, "player to analyze: 1 (werewolves), 2 (vampires) or 0 for both")
+	fs.StringVar(&c.engine, "engine", "", "spec of the min max IA searching the positions, like minmax:battles=0.02 (the heuristic of each player if empty)")
+	fs.StringVar(&c.format, "format", "text", "output format: text or json")
+	fs.BoolVar(&c.explain, "explain", false, "break down the score of each position by the terms of the heuristic and list the battles it considered")
+}
+
+func (c *analyzeCommand) run(args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return usageErrorf("please provide the path of the replay file")
+	}
+
+	if c.depth > math.MaxUint8 {
+		return usageErrorf("invalid depth %d, should be at most %d", c.depth, math.MaxUint8)
+	}
+
+	opts := analysis.Options{Timeout: c.timeout, Depth: uint8(c.depth), Threshold: c.threshold, Engine: c.engine, Explain: c.explain}
+	switch c.player {
+	case 0:
+		opts.Players = []tournament.Perspective{tournament.Player1, tournament.Player2}
+	case 1:
+		opts.Players = []tournament.Perspective{tournament.Player1}
+	case 2:
+		opts.Players = []tournament.Perspective{tournament.Player2}
+	default:
+		return usageErrorf("invalid player %d, should be 0, 1 or 2", c.player)
+	}
+
+	var write func(r *analysis.Report, w io.Writer) error
+	switch c.format {
+	case "text":
+		write = (*analysis.Report).WriteText
+	case "json":
+		write = (*analysis.Report).WriteJSON
+	default:
+		return usageErrorf("invalid format %s, should be text or json", c.format)
+	}
+
+	replay, err := tournament.LoadMatchSummary(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load replay file: %s", err)
+	}
+
+	report, err := analysis.Analyze(replay, opts)
+	if err != nil {
+		return fmt.Errorf("failed to analyze replay: %s", err)
+	}
+
+	if err = write(report, out); err != nil {
+		return fmt.Errorf("failed to write report: %s", err)
+	}
+	return nil
+}
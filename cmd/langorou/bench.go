@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+type benchCommand struct {
+	spec    string
+	timeout time.Duration
+	count   int
+	player  int
+}
+
+func (c *benchCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.spec, "ia", "minmax", "spec of the min max IA to measure, like minmax:battles=0.02")
+	fs.DurationVar(&c.timeout, "timeout", 2*time.Second, "time budget of each search")
+	fs.IntVar(&c.count, "count", 1, "number of searches of each position")
+	fs.IntVar(&c.player, "player", 1, "player searching the starting position of the maps: 1 (werewolves) or 2 (vampires)")
+}
+
+// xmlMap is the format of the maps of the twilight server
+type xmlMap struct {
+	Rows       int       `xml:"Rows,attr"`
+	Columns    int       `xml:"Columns,attr"`
+	Humans     []xmlCell `xml:"Humans"`
+	Werewolves []xmlCell `xml:"Werewolves"`
+	Vampires   []xmlCell `xml:"Vampires"`
+}
+
+type xmlCell struct {
+	X     int `xml:"X,attr"`
+	Y     int `xml:"Y,attr"`
+	Count int `xml:"Count,attr"`
+}
+
+// loadMap returns the starting position of a map seen by the werewolves, or by the vampires if vampires is true
+func loadMap(path string, vampires bool) (*model.State, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m xmlMap
+	if err = xml.NewDecoder(f).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid map %s: %s", path, err)
+	}
+	if m.Rows <= 0 || m.Rows > 255 || m.Columns <= 0 || m.Columns > 255 {
+		return nil, fmt.Errorf("invalid map %s: size %dx%d", path, m.Rows, m.Columns)
+	}
+
+	ally, enemy := model.Ally, model.Enemy
+	if vampires {
+		ally, enemy = enemy, ally
+	}
+
+	s := model.NewState(uint8(m.Rows), uint8(m.Columns))
+	for _, group := range []struct {
+		race  model.Race
+		cells []xmlCell
+	}{{model.Neutral, m.Humans}, {ally, m.Werewolves}, {enemy, m.Vampires}} {
+		for _, c := range group.cells {
+			if c.X < 0 || c.X >= m.Columns || c.Y < 0 || c.Y >= m.Rows || c.Count <= 0 || c.Count > 255 {
+				return nil, fmt.Errorf("invalid map %s: cell %d %d with %d units", path, c.X, c.Y, c.Count)
+			}
+			s.SetCell(model.Coordinates{X: uint8(c.X), Y: uint8(c.Y)}, group.race, uint8(c.Count))
+		}
+	}
+	return s, nil
+}
+
+type position struct {
+	name  string
+	state *model.State
+}
+
+func (c *benchCommand) positions(paths []string) ([]position, error) {
+	if len(paths) == 0 {
+		return []position{
+			{"simple", model.GenerateSimpleState()},
+			{"complicated", model.GenerateComplicatedState()},
+		}, nil
+	}
+
+	var positions []position
+	for _, path := range paths {
+		s, err := loadMap(path, c.player == 2)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position{filepath.Base(path), s})
+	}
+	return positions, nil
+}
+
+func (c *benchCommand) run(args []string, out io.Writer) error {
+	if c.player != 1 && c.player != 2 {
+		return usageErrorf("invalid player %d, should be 1 or 2", c.player)
+	}
+	if c.count <= 0 || c.timeout <= 0 {
+		return usageErrorf("-count and -timeout should be positive")
+	}
+	ia, err := newIA("ia", c.spec)
+	if err != nil {
+		return err
+	}
+	searcher, ok := ia.(client.Searcher)
+	if !ok {
+		return usageErrorf("invalid -ia: IA %s has no heuristic to measure", c.spec)
+	}
+
+	positions, err := c.positions(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s, %s per search\n\n", ia.Name(), c.timeout)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "position\tdepth\tnodes\tnodes/s\ttt entries\tscore\t")
+
+	var totalNodes uint64
+	var totalTime time.Duration
+	for _, p := range positions {
+		for i := 0; i < c.count; i++ {
+			// A fresh heuristic for each search so that they are comparable
+			h := searcher.Heuristic()
+			start := time.Now()
+			eval := h.SearchWithTimeout(p.state.Copy(false), c.timeout)
+			elapsed := time.Since(start)
+
+			totalNodes += eval.Nodes
+			totalTime += elapsed
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.0f\t%d\t%.2f\t\n", p.name, eval.Depth, eval.Nodes, float64(eval.Nodes)/elapsed.Seconds(), eval.TTSize, eval.Score)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n%d searches, %.0f nodes/s\n", len(positions)*c.count, float64(totalNodes)/totalTime.Seconds())
+	return nil
+}
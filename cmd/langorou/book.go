@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/langorou/langorou/pkg/analysis"
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+type bookCommand struct {
+	outPath string
+	plies   int
+	spec    string
+	timeout time.Duration
+	weight  float64
+}
+
+func (c *bookCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.outPath, "out", "book.json", "path of the opening book to write, to use with the book option of the min max IAs")
+	fs.IntVar(&c.plies, "plies", 4, "number of coups of the winner of each replay added to the book")
+	fs.StringVar(&c.spec, "ia", "minmax", "spec of the min max IA searching the starting position of the maps")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "time budget to search the starting position of each map")
+	fs.Float64Var(&c.weight, "searchWeight", 1, "weight of the coups found by searching the maps, each coup of a replay weighs 1")
+}
+
+// bookPaths returns the maps and the replays given, with the ones of the folders among them. The files given are
+// maps if their extension is .xml and replays otherwise
+func bookPaths(paths []string) (maps, replays []string, err error) {
+	for _, root := range paths {
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			switch ext := filepath.Ext(path); {
+			case ext == ".xml":
+				maps = append(maps, path)
+			case path == root || ext == tournament.ReplayExt || ext == ".json":
+				replays = append(replays, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return maps, replays, nil
+}
+
+func (c *bookCommand) run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("please provide the maps, the replays or the folders of the matches of a tournament")
+	}
+	if c.plies < 0 || c.timeout <= 0 || c.weight <= 0 {
+		return usageErrorf("-plies should not be negative, -timeout and -searchWeight should be positive")
+	}
+	maps, replays, err := bookPaths(args)
+	if err != nil {
+		return err
+	}
+
+	book := client.NewBook()
+
+	var coups int
+	for _, path := range replays {
+		mr, err := tournament.LoadMatchSummary(path)
+		if err != nil {
+			return fmt.Errorf("failed to load replay file %s: %s", path, err)
+		}
+		coups += analysis.AddReplayToBook(book, mr, c.plies)
+	}
+	if len(replays) > 0 {
+		fmt.Fprintf(out, "%d coups of the winners of %d replays\n", coups, len(replays))
+	}
+
+	if len(maps) > 0 {
+		ia, err := newIA("ia", c.spec)
+		if err != nil {
+			return err
+		}
+		searcher, ok := ia.(client.Searcher)
+		if !ok {
+			return usageErrorf("invalid -ia: IA %s can't search the maps, it has no heuristic", c.spec)
+		}
+
+		for _, path := range maps {
+			// The player starting the game isn't known, search the position of both
+			for _, vampires := range []bool{false, true} {
+				s, err := loadMap(path, vampires)
+				if err != nil {
+					return err
+				}
+				eval := analysis.AddSearchToBook(book, s, searcher.Heuristic(), c.timeout, c.weight)
+				side := "werewolves"
+				if vampires {
+					side = "vampires"
+				}
+				fmt.Fprintf(out, "%s (%s): depth %d, score %.2f, coup %v\n", filepath.Base(path), side, eval.Depth, eval.Score, eval.Coup)
+			}
+		}
+	}
+
+	if err = book.Save(c.outPath); err != nil {
+		return err
+	}
+	log.Printf("%d positions saved at %s, play with -ia minmax:book=%s or -book %s", book.Len(), c.outPath, c.outPath, c.outPath)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/logging"
+	"github.com/langorou/twilight/server"
+
+	// Register the linear and remote IAs so that every spec flag accepts them
+	_ "github.com/langorou/langorou/pkg/learn"
+	_ "github.com/langorou/langorou/pkg/remote"
+)
+
+// mapFlags choose the map of the games of an in-process server
+type mapFlags struct {
+	path    string
+	random  bool
+	rows    int
+	columns int
+	humans  int
+	monster int
+}
+
+func (m *mapFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.path, "map", "", "path to the map to load (or save if randomly generating)")
+	fs.BoolVar(&m.random, "rand", false, "use a randomly generated map")
+	fs.IntVar(&m.rows, "rows", 10, "total number of rows of the random map")
+	fs.IntVar(&m.columns, "columns", 10, "total number of columns of the random map")
+	fs.IntVar(&m.humans, "humans", 16, "quantity of humans group of the random map")
+	fs.IntVar(&m.monster, "monster", 8, "quantity of monster in the start case of the random map")
+}
+
+func (m *mapFlags) check() error {
+	if m.path == "" && !m.random {
+		return usageErrorf("please specify a map with -map or -rand")
+	}
+	return nil
+}
+
+// startServer starts a twilight server on the map in the background. With a random port, the port is sent on
+// portUsed and the outcome of the game on gameOutcomeCh when they are not nil
+func (m *mapFlags) startServer(timeout time.Duration, randomPort bool, portUsed chan int, noWebApp bool, gameOutcomeCh chan server.GameOutcome) {
+	go server.StartServer(m.path, m.random, m.rows, m.columns, m.humans, m.monster, timeout, randomPort, portUsed, noWebApp, gameOutcomeCh)
+}
+
+// specUsage appends the registered IAs to the usage of a spec flag
+func specUsage(usage string) string {
+	return usage + ", like minmax:timeout=1s,battles=0.02, the IAs are:\n" + strings.TrimRight(client.Usage(), "\n")
+}
+
+// newIA creates the IA of a spec flag, an invalid spec is an usage error
+func newIA(flagName, spec string) (client.IA, error) {
+	ia, err := client.NewIA(spec)
+	if err != nil {
+		return nil, usageErrorf("invalid -%s: %s", flagName, err)
+	}
+	return ia, nil
+}
+
+// specList is a spec flag which can be repeated
+type specList []string
+
+func (l *specList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *specList) Set(spec string) error {
+	*l = append(*l, spec)
+	return nil
+}
+
+// logFlags configure the default logger
+type logFlags struct {
+	level  string
+	format string
+}
+
+func (l *logFlags) register(fs *flag.FlagSet, level string) {
+	fs.StringVar(&l.level, "logLevel", level, "minimum level of the logs: debug, info, warn or error")
+	fs.StringVar(&l.format, "logFormat", "text", "format of the logs: text or json")
+}
+
+func (l *logFlags) setup() error {
+	logger, err := logging.Parse(os.Stderr, l.level, l.format)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	logging.SetDefault(logger)
+	return nil
+}
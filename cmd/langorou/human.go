@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/logging"
+	"github.com/langorou/twilight/server"
+)
+
+type humanCommand struct {
+	maps     mapFlags
+	addr     string
+	name     string
+	timeout  time.Duration
+	opponent string
+	p2       bool
+	color    bool
+	logPath  string
+	// in is where the moves are read, os.Stdin if nil
+	in io.Reader
+}
+
+func (c *humanCommand) flags(fs *flag.FlagSet) {
+	c.maps.register(fs)
+	fs.StringVar(&c.addr, "addr", "", "address of a twilight server to play on, a server and an opponent are started in-process if empty")
+	fs.StringVar(&c.name, "name", "human", "name of the player")
+	fs.DurationVar(&c.timeout, "timeout", 10*time.Minute, "time allowed for each move by the in-process server")
+	fs.StringVar(&c.opponent, "opponent", "minmax:timeout=1500ms", specUsage("spec of the in-process opponent"))
+	fs.BoolVar(&c.p2, "p2", false, "play second (vampires) against the in-process opponent")
+	fs.BoolVar(&c.color, "color", true, "color the races with ANSI escape codes")
+	fs.StringVar(&c.logPath, "log", "", "file where the logs of the clients are written, they are discarded if empty")
+}
+
+func (c *humanCommand) run(args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return usageErrorf("unexpected arguments %v", args)
+	}
+	var opponentIA client.IA
+	if c.addr == "" {
+		if err := c.maps.check(); err != nil {
+			return err
+		}
+		var err error
+		if opponentIA, err = newIA("opponent", c.opponent); err != nil {
+			return err
+		}
+	}
+
+	// The logs of the clients would mess the rendering of the grid
+	if c.logPath != "" {
+		f, err := os.Create(c.logPath)
+		if err != nil {
+			return fmt.Errorf("creating the log file: %s", err)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+		logging.SetDefault(logging.New(f, logging.DebugLevel, logging.TextFormat))
+	} else {
+		log.SetOutput(ioutil.Discard)
+		logging.SetDefault(logging.Discard())
+	}
+
+	in := c.in
+	if in == nil {
+		in = os.Stdin
+	}
+	ia := client.NewHumanIA(in, out, c.color)
+
+	if c.addr != "" {
+		player, err := client.NewTCPClient(c.addr, c.name, ia)
+		if err != nil {
+			return fmt.Errorf("connecting to %s: %s", c.addr, err)
+		}
+		return player.Start()
+	}
+
+	portUsed := make(chan int, 1)
+	gameOutcomeCh := make(chan server.GameOutcome, 1)
+	c.maps.startServer(c.timeout, true, portUsed, true, gameOutcomeCh)
+	addr := fmt.Sprintf("localhost:%d", <-portUsed)
+
+	player, err := client.NewTCPClient(addr, c.name, ia)
+	if err != nil {
+		return err
+	}
+	opponent, err := client.NewTCPClient(addr, opponentIA.Name(), opponentIA)
+	if err != nil {
+		return err
+	}
+
+	// The first player to send its name plays the werewolves
+	first, second := &player, &opponent
+	if c.p2 {
+		first, second = second, first
+	}
+	if err = first.Init(); err != nil {
+		return fmt.Errorf("fail to init player 1: %s", err)
+	}
+	if err = second.Init(); err != nil {
+		return fmt.Errorf("fail to init player 2: %s", err)
+	}
+
+	go opponent.Play()
+	go player.Play()
+
+	outcome := <-gameOutcomeCh
+	us, them := outcome.P1Eff, outcome.P2Eff
+	if c.p2 {
+		us, them = them, us
+	}
+	switch {
+	case us > them:
+		fmt.Fprintf(out, "\nyou won %d - %d in %d turns\n", us, them, outcome.Turn)
+	case us < them:
+		fmt.Fprintf(out, "\nyou lost %d - %d in %d turns\n", us, them, outcome.Turn)
+	default:
+		fmt.Fprintf(out, "\ndraw %d - %d in %d turns\n", us, them, outcome.Turn)
+	}
+	return nil
+}
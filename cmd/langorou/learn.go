@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/langorou/langorou/pkg/learn"
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+type datasetCommand struct {
+	outPath string
+}
+
+func (c *datasetCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.outPath, "out", "dataset.csv", "path of the CSV dataset to write")
+}
+
+// replayPaths returns the paths given, with the replays of the folders among them
+func replayPaths(paths []string) ([]string, error) {
+	var replays []string
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(path)
+			// The files given are replays whatever their extension
+			if !info.IsDir() && (path == root || ext == tournament.ReplayExt || ext == ".json") {
+				replays = append(replays, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return replays, nil
+}
+
+func (c *datasetCommand) run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("please provide the replays or the folders of the matches of a tournament")
+	}
+	paths, err := replayPaths(args)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(c.outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dw := learn.NewDatasetWriter(f)
+	var n int
+	for _, path := range paths {
+		mr, err := tournament.LoadMatchSummary(path)
+		if err != nil {
+			return fmt.Errorf("failed to load replay file %s: %s", path, err)
+		}
+		for _, s := range learn.Samples(mr) {
+			if err = dw.Write(s); err != nil {
+				return err
+			}
+			n++
+		}
+	}
+	if err = dw.Flush(); err != nil {
+		return err
+	}
+
+	log.Printf("%d samples of %d matches written to %s", n, len(paths), c.outPath)
+	return f.Sync()
+}
+
+type trainCommand struct {
+	outPath string
+	name    string
+	opts    learn.TrainOptions
+}
+
+func (c *trainCommand) flags(fs *flag.FlagSet) {
+	def := learn.DefaultTrainOptions()
+	fs.StringVar(&c.outPath, "out", "weights.json", "path of the weights to write, to use with the linear IA")
+	fs.StringVar(&c.name, "name", "", "name of the weights in the name of the IA, the base name of -out if empty")
+	fs.IntVar(&c.opts.Epochs, "epochs", def.Epochs, "number of gradient descent steps")
+	fs.Float64Var(&c.opts.LearningRate, "rate", def.LearningRate, "learning rate of the gradient descent")
+	fs.Float64Var(&c.opts.L2, "l2", def.L2, "coefficient of the L2 regularization")
+}
+
+func (c *trainCommand) run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("please provide the datasets written by langorou dataset")
+	}
+
+	var samples []learn.Sample
+	for _, path := range args {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		s, err := learn.ReadDataset(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("invalid dataset %s: %s", path, err)
+		}
+		samples = append(samples, s...)
+	}
+
+	w, err := learn.Train(samples, c.opts)
+	if err != nil {
+		return err
+	}
+	w.Name = c.name
+	if w.Name == "" {
+		w.Name = filepath.Base(c.outPath)
+		w.Name = w.Name[:len(w.Name)-len(filepath.Ext(w.Name))]
+	}
+
+	fmt.Fprintf(out, "%d samples, log loss %.4f, accuracy %.1f%%\n\n", len(samples), learn.LogLoss(w, samples), 100*learn.Accuracy(w, samples))
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	names := make([]string, 0, len(w.Weights))
+	for name := range w.Weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%.6g\n", name, w.Weights[name])
+	}
+	fmt.Fprintf(tw, "bias\t%.6g\n", w.Bias)
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+
+	if err = w.Save(c.outPath); err != nil {
+		return err
+	}
+	log.Printf("weights saved at %s, play with -ia linear:weights=%s", c.outPath, c.outPath)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes of the subcommands
+const (
+	exitOK      = 0
+	exitFailure = 1
+	// exitUsage is used for invalid flags or arguments, like the flag package does
+	exitUsage = 2
+)
+
+// command is a subcommand, its flags are registered on a fresh flag set before running it
+type command interface {
+	flags(fs *flag.FlagSet)
+	run(args []string, out io.Writer) error
+}
+
+type commandInfo struct {
+	name string
+	// args describes the positional arguments in the usage line
+	args    string
+	summary string
+	new     func() command
+}
+
+var commands = []commandInfo{
+	{"play", "[flags] <host> <port>", "play on a twilight server, reconnecting when the connection drops", func() command { return &playCommand{} }},
+	{"serve-and-play", "[flags]", "start a twilight server and its web app, and connect our players to it", func() command { return &serveCommand{} }},
+	{"human", "[flags]", "play in the terminal against an in-process IA, or on a twilight server with -addr", func() command { return &humanCommand{} }},
+	{"serve-ia", "[flags]", "serve an IA over HTTP for the remote IA", func() command { return &serveIACommand{} }},
+	{"tournament", "[flags]", "play every participant against the others and save the results in -out", func() command { return &tournamentCommand{} }},
+	{"replay", "[flags] <replay>", "view, export or convert a replay", func() command { return &replayCommand{} }},
+	{"analyze", "[flags] <replay>", "search the positions of a replay again and flag the blunders", func() command { return &analyzeCommand{} }},
+	{"dataset", "[flags] <replay or folder>...", "write the positions of replays with the outcome of their match as a CSV dataset", func() command { return &datasetCommand{} }},
+	{"train", "[flags] <dataset.csv>...", "fit the weights of the linear IA on datasets with a logistic regression", func() command { return &trainCommand{} }},
+	{"book", "[flags] <map.xml, replay or folder>...", "build an opening book from the winners of replays and deep searches of maps", func() command { return &bookCommand{} }},
+	{"bench", "[flags] [map.xml...]", "measure the depth and the speed of the search of an IA on maps", func() command { return &benchCommand{} }},
+}
+
+// usageError is returned by the subcommands for invalid flags or arguments
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: langorou <command> [flags] [arguments]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-15s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun langorou help <command> for the flags of a command.\n")
+	fmt.Fprintf(w, "The exit code is %d on success, %d on failure and %d for invalid flags or arguments.\n", exitOK, exitFailure, exitUsage)
+}
+
+func lookup(name string) (commandInfo, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return commandInfo{}, false
+}
+
+// run runs the subcommand given in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	name, args := args[0], args[1:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) == 0 {
+			usage(stdout)
+			return exitOK
+		}
+		// langorou help <command> is the same as langorou <command> -h
+		name, args = args[0], []string{"-h"}
+	}
+
+	info, ok := lookup(name)
+	if !ok {
+		fmt.Fprintf(stderr, "langorou: unknown command %q\n\n", name)
+		usage(stderr)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet(info.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: langorou %s %s\n\n%s\n\nflags:\n", info.name, info.args, info.summary)
+		fs.PrintDefaults()
+	}
+
+	cmd := info.new()
+	cmd.flags(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	err := cmd.run(fs.Args(), stdout)
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "langorou %s: %s\n", info.name, err)
+	if _, ok := err.(usageError); ok {
+		fs.Usage()
+		return exitUsage
+	}
+	return exitFailure
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCommand(args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	code, _, stderr := runCommand()
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "serve-and-play")
+
+	code, stdout, _ := runCommand("help")
+	assert.Equal(t, exitOK, code)
+	for _, c := range commands {
+		assert.Contains(t, stdout, c.name)
+	}
+
+	code, _, stderr = runCommand("help", "play")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "usage: langorou play [flags] <host> <port>")
+	assert.Contains(t, stderr, "-retries")
+
+	code, _, stderr = runCommand("fly")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, `unknown command "fly"`)
+
+	code, _, stderr = runCommand("play", "-unknown")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "flag provided but not defined: -unknown")
+
+	code, _, stderr = runCommand("play", "localhost", "99999")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid port 99999")
+
+	code, _, stderr = runCommand("play", "-ia", "dumb", "-config", "langorou.yaml", "localhost", "5555")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-ia can't be used with -config or -remote")
+
+	code, _, stderr = runCommand("serve-and-play")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "please specify a map with -map or -rand")
+
+	code, _, stderr = runCommand("human")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "please specify a map with -map or -rand")
+
+	code, _, stderr = runCommand("human", "-rand", "-opponent", "minmax:depth=3")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid -opponent: IA minmax: unknown options depth")
+
+	code, _, stderr = runCommand("serve-ia", "-ia", "minmax:depth=3")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid -ia: IA minmax: unknown options depth")
+
+	code, _, stderr = runCommand("tournament", "-ia", "dumb", "-ia", "minmax:depth=3")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid -ia: IA minmax: unknown options depth")
+
+	code, _, stderr = runCommand("analyze", "-depth", "300", "missing.lgr")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid depth 300")
+
+	code, _, stderr = runCommand("replay", "missing.lgr")
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, stderr, "langorou replay: failed to load replay file")
+}
+
+func TestPlayConfig(t *testing.T) {
+	c := &playCommand{}
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	c.flags(fs)
+	require.NoError(t, fs.Parse([]string{"-ia", "minmax:timeout=1s", "-name", "bob"}))
+
+	cfg, err := c.config()
+	require.NoError(t, err)
+	assert.Equal(t, "bob", cfg.Name)
+	assert.Equal(t, "minmax:timeout=1s", cfg.IA)
+	assert.Contains(t, cfg.String(), "ia: minmax:timeout=1s")
+}
+
+func TestReplayInfo(t *testing.T) {
+	code, stdout, stderr := runCommand("replay", "-info", "../../pkg/tournament/testdata/thetrap.json")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "Map:")
+	assert.Contains(t, stdout, "Player 1:")
+}
+
+func TestBench(t *testing.T) {
+	code, stdout, stderr := runCommand("bench", "-timeout", "50ms", "-player", "2", "../../maps/thetrap.xml")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "thetrap.xml")
+	assert.Contains(t, stdout, "1 searches")
+
+	code, _, stderr = runCommand("bench", "-ia", "dumb")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "IA dumb has no heuristic to measure")
+}
+
+func TestLoadMap(t *testing.T) {
+	s, err := loadMap("../../maps/testmap.xml", false)
+	require.NoError(t, err)
+	vampires, err := loadMap("../../maps/testmap.xml", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, s.Height, vampires.Height)
+	assert.Equal(t, s.Width, vampires.Width)
+	assert.Len(t, vampires.Grid, len(s.Grid))
+	for coord, cell := range s.Grid {
+		assert.Equal(t, cell.Count, vampires.Grid[coord].Count)
+	}
+
+	_, err = loadMap("main.go", false)
+	assert.Error(t, err)
+}
+
+func TestDatasetAndTrain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "langorou")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dataset, weights := filepath.Join(dir, "data.csv"), filepath.Join(dir, "w.json")
+
+	code, _, stderr := runCommand("dataset", "-out", dataset, "../../pkg/tournament/testdata")
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, stderr := runCommand("train", "-out", weights, "-epochs", "50", dataset)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "30 samples")
+
+	code, stdout, stderr = runCommand("bench", "-timeout", "50ms", "-ia", "linear:weights="+weights, "../../maps/thetrap.xml")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "_w, 50ms per search")
+
+	code, _, _ = runCommand("train")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestBook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "langorou")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	book := filepath.Join(dir, "book.json")
+
+	code, stdout, stderr := runCommand("book", "-out", book, "-timeout", "50ms", "../../pkg/tournament/testdata", "../../maps/thetrap.xml")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "of the winners of 1 replays")
+	assert.Contains(t, stdout, "thetrap.xml (vampires)")
+
+	b, err := client.LoadBook(book)
+	require.NoError(t, err)
+	assert.NotZero(t, b.Len())
+
+	code, _, stderr = runCommand("bench", "-timeout", "50ms", "-ia", "minmax:book="+book, "../../maps/thetrap.xml")
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, _, _ = runCommand("book")
+	assert.Equal(t, exitUsage, code)
+	code, _, _ = runCommand("book", "-ia", "dumb", "../../maps/thetrap.xml")
+	assert.Equal(t, exitUsage, code)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/config"
+	"github.com/langorou/langorou/pkg/metrics"
+	"github.com/langorou/langorou/pkg/remote"
+)
+
+type playCommand struct {
+	fs            *flag.FlagSet
+	configPath    string
+	name          string
+	deadline      time.Duration
+	margin        time.Duration
+	retries       int
+	book          string
+	spec          string
+	remoteURL     string
+	remoteTimeout time.Duration
+	metricsAddr   string
+	log           logFlags
+}
+
+func (c *playCommand) flags(fs *flag.FlagSet) {
+	defaults := config.Default()
+	c.fs = fs
+	fs.StringVar(&c.configPath, "config", "", "path to a YAML or JSON configuration file, see pkg/config (the flags and the LANGOROU_* environment variables override it)")
+	fs.StringVar(&c.name, "name", defaults.Name, "name of the player")
+	fs.DurationVar(&c.deadline, "deadline", defaults.Deadline.Duration, "time allowed by the server to play, a coup is always sent before it (0 to disable)")
+	fs.DurationVar(&c.margin, "margin", defaults.Margin.Duration, "time kept before the deadline to send the coup")
+	fs.IntVar(&c.retries, "retries", defaults.Retries, "maximum number of consecutive reconnections when the connection to the server drops (-1 to retry forever)")
+	fs.StringVar(&c.book, "book", defaults.Book, "path of the opening book of the min max IA, built by langorou book (none if empty)")
+	fs.StringVar(&c.spec, "ia", "", specUsage("spec of the IA to play with instead of the configured one"))
+	fs.StringVar(&c.remoteURL, "remote", "", "URL of a remote IA service to play with instead of the local min max (see pkg/remote)")
+	fs.DurationVar(&c.remoteTimeout, "remoteTimeout", defaults.Remote.Timeout.Duration, "time allowed to the remote IA service before falling back to a local dumb IA")
+	fs.StringVar(&c.metricsAddr, "metrics", "", "address on which to serve the metrics at /metrics, for instance :9100 (disabled if empty)")
+	c.log.register(fs, "info")
+}
+
+// config merges the defaults, the configuration file, the environment and the flags given on the command line, in
+// this order
+func (c *playCommand) config() (config.Config, error) {
+	cfg := config.Default()
+	if c.configPath != "" {
+		if err := cfg.Load(c.configPath); err != nil {
+			return cfg, fmt.Errorf("loading the configuration: %s", err)
+		}
+	}
+	if err := cfg.ApplyEnv(os.Environ()); err != nil {
+		return cfg, fmt.Errorf("reading the environment: %s", err)
+	}
+	c.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			cfg.Name = c.name
+		case "deadline":
+			cfg.Deadline.Duration = c.deadline
+		case "margin":
+			cfg.Margin.Duration = c.margin
+		case "retries":
+			cfg.Retries = c.retries
+		case "book":
+			cfg.Book = c.book
+		case "remote":
+			cfg.IA = config.RemoteIA
+			cfg.Remote.URL = c.remoteURL
+		case "remoteTimeout":
+			cfg.Remote.Timeout.Duration = c.remoteTimeout
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid configuration: %s", err)
+	}
+	if c.spec != "" {
+		// The spec replaces the configured IA, it is validated when the IA is built
+		cfg.IA = c.spec
+	}
+	return cfg, nil
+}
+
+func (c *playCommand) run(args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return usageErrorf("please provide the IP address and the port of the server")
+	}
+	if _, err := strconv.ParseUint(args[1], 10, 16); err != nil { // 0 <= port <= 65535
+		return usageErrorf("invalid port %s, should be between 0 and 65535", args[1])
+	}
+	if c.spec != "" && (c.configPath != "" || c.remoteURL != "") {
+		return usageErrorf("-ia can't be used with -config or -remote, the spec replaces the configured IA")
+	}
+	if err := c.log.setup(); err != nil {
+		return err
+	}
+
+	cfg, err := c.config()
+	if err != nil {
+		return err
+	}
+	log.Printf("effective configuration:\n%s", cfg)
+
+	var ia client.IA
+	switch {
+	case c.spec != "":
+		if ia, err = newIA("ia", c.spec); err != nil {
+			return err
+		}
+		log.Printf("playing with the IA %s", c.spec)
+	case cfg.IA == config.MinMaxIA:
+		minMax := client.NewMinMaxIAP(cfg.Timeout.Duration, cfg.Heuristic.Params())
+		minMax.SetEndgameLimits(cfg.Endgame.Limits())
+		if cfg.Book != "" {
+			book, err := client.LoadBook(cfg.Book)
+			if err != nil {
+				return err
+			}
+			log.Printf("opening book %s with %d positions", cfg.Book, book.Len())
+			minMax.SetBook(book)
+		}
+		ia = minMax
+	case cfg.IA == config.DumbIA:
+		ia = client.NewDumbIA()
+	case cfg.IA == config.RemoteIA:
+		ia = remote.NewIA(cfg.Remote.URL, cfg.Remote.Timeout.Duration)
+	}
+
+	var playerMetrics *client.PlayerMetrics
+	if c.metricsAddr != "" {
+		registry := metrics.NewRegistry()
+		playerMetrics = client.NewPlayerMetrics(registry)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", registry.Handler())
+		go func() {
+			log.Printf("serving the metrics on %s/metrics", c.metricsAddr)
+			log.Fatalf("error serving the metrics: %s", http.ListenAndServe(c.metricsAddr, mux))
+		}()
+	}
+
+	addr := net.JoinHostPort(args[0], args[1])
+	log.Printf("connecting to %s with name: %s", addr, cfg.Name)
+
+	reconnect := client.NewDefaultReconnect()
+	reconnect.MaxRetries = cfg.Retries
+
+	return client.PlayWithReconnect(addr, cfg.Name, ia, reconnect, func(tc *client.TCPClient) {
+		tc.SetMoveDeadline(client.MoveDeadline{Deadline: cfg.Deadline.Duration, Margin: cfg.Margin.Duration})
+		tc.SetMetrics(playerMetrics)
+	})
+}
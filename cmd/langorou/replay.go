@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/langorou/langorou/pkg/replay"
+	"github.com/langorou/langorou/pkg/tournament"
+	"github.com/langorou/twilight/server"
+)
+
+type replayCommand struct {
+	term        bool
+	noColor     bool
+	gifPath     string
+	svgDir      string
+	cellSize    int
+	delay       int
+	convertPath string
+	info        bool
+}
+
+func (c *replayCommand) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.term, "term", false, "step through the replay in the terminal instead of starting the web app")
+	fs.BoolVar(&c.noColor, "nocolor", false, "disable colors in the terminal")
+	fs.StringVar(&c.gifPath, "gif", "", "export the replay as an animated GIF at this path instead of starting the web app")
+	fs.StringVar(&c.svgDir, "svg", "", "export each frame of the replay as an SVG file in this folder instead of starting the web app")
+	fs.IntVar(&c.cellSize, "cell", replay.DefaultExportOptions().CellSize, "size in pixels of a cell in the exported frames")
+	fs.IntVar(&c.delay, "delay", replay.DefaultExportOptions().Delay, "delay between two frames of the GIF in 100ths of a second")
+	fs.StringVar(&c.convertPath, "convert", "", "convert the replay to this path, as JSON if it ends with .json and in the compact format otherwise")
+	fs.BoolVar(&c.info, "info", false, "only print the metadata of the replay")
+}
+
+func (c *replayCommand) run(args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return usageErrorf("please provide the path of the replay file")
+	}
+	replayPath := args[0]
+
+	if c.info {
+		return printInfo(replayPath, out)
+	}
+
+	match, err := tournament.LoadMatchSummary(replayPath)
+	if err != nil {
+		return fmt.Errorf("failed to load replay file: %s", err)
+	}
+
+	if c.convertPath != "" {
+		if filepath.Ext(c.convertPath) == ".json" {
+			err = match.SaveJSON(c.convertPath)
+		} else {
+			err = match.SaveReplay(c.convertPath)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to convert replay: %s", err)
+		}
+		log.Printf("replay converted to %s", c.convertPath)
+		return nil
+	}
+
+	if c.gifPath != "" || c.svgDir != "" {
+		return c.export(match)
+	}
+
+	if c.term {
+		if err = replay.NewViewer(match, os.Stdin, out, !c.noColor).Run(); err != nil {
+			return fmt.Errorf("failed to view replay: %s", err)
+		}
+		return nil
+	}
+
+	server.StartWebAppFromHistory(match.History)
+	return nil
+}
+
+func (c *replayCommand) export(match *tournament.MatchSummary) error {
+	opts := replay.ExportOptions{CellSize: c.cellSize, Delay: c.delay}
+
+	if c.gifPath != "" {
+		f, err := os.Create(c.gifPath)
+		if err != nil {
+			return fmt.Errorf("failed to create GIF: %s", err)
+		}
+		defer f.Close()
+
+		if err = replay.WriteGIF(f, match, opts); err != nil {
+			return fmt.Errorf("failed to export GIF: %s", err)
+		}
+		log.Printf("GIF saved at %s", c.gifPath)
+	}
+
+	if c.svgDir != "" {
+		if err := replay.WriteSVGFrames(c.svgDir, match, opts); err != nil {
+			return fmt.Errorf("failed to export SVG frames: %s", err)
+		}
+		log.Printf("SVG frames saved in %s", c.svgDir)
+	}
+	return nil
+}
+
+// printInfo prints the metadata of the replay, compact replays are not fully decoded
+func printInfo(replayPath string, out io.Writer) error {
+	var header tournament.ReplayHeader
+
+	f, err := os.Open(replayPath)
+	if err != nil {
+		return fmt.Errorf("failed to open replay file: %s", err)
+	}
+	defer f.Close()
+
+	if h, err := tournament.ReadReplayHeader(f); err == nil {
+		header = *h
+	} else {
+		match, err := tournament.LoadMatchSummary(replayPath)
+		if err != nil {
+			return fmt.Errorf("failed to load replay file: %s", err)
+		}
+		header = match.Header()
+	}
+
+	fmt.Fprintf(out, "Map:      %s\n", header.MapName)
+	fmt.Fprintf(out, "Player 1: %s\n", header.Player1.Name())
+	fmt.Fprintf(out, "Player 2: %s\n", header.Player2.Name())
+	fmt.Fprintf(out, "Outcome:  %d - %d after %d turns (%d frames)\n", header.Player1Eff, header.Player2Eff, header.EndTurn, header.Frames)
+	fmt.Fprintf(out, "Seed:     %d\n", header.Seed)
+	fmt.Fprintf(out, "Version:  %d\n", header.Version)
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"net/http"
+	_ "net/http/pprof"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/metrics"
+)
+
+// serverAddr is the address of the twilight server when it doesn't use a random port
+const serverAddr = "localhost:5555"
+
+// serverStartTimeout is the time given to the server to listen
+const serverStartTimeout = 5 * time.Second
+
+type serveCommand struct {
+	maps        mapFlags
+	timeoutS    int
+	p1, p2      string
+	wait        time.Duration
+	linger      time.Duration
+	metricsAddr string
+}
+
+func (c *serveCommand) flags(fs *flag.FlagSet) {
+	c.maps.register(fs)
+	fs.IntVar(&c.timeoutS, "timeout", 8, "timeout in seconds for each move")
+	fs.StringVar(&c.p1, "p1", "minmax:timeout=1500ms", specUsage("spec of the IA of player 1 (werewolves), empty to leave the seat to another player"))
+	fs.StringVar(&c.p2, "p2", "minmax:timeout=500ms", "spec of the IA of player 2 (vampires), empty to leave the seat to another player")
+	fs.DurationVar(&c.wait, "wait", 10*time.Second, "time given to the other player to connect first when -p1 is empty")
+	fs.DurationVar(&c.linger, "linger", 5*time.Minute, "time the web app stays up after the game")
+	fs.StringVar(&c.metricsAddr, "metrics", "localhost:6060", "address on which to serve the metrics of our players at /metrics, next to pprof (disabled if empty)")
+}
+
+// connect creates the player of a spec and sends its name to the server
+func (c *serveCommand) connect(flagName, spec string, registry *metrics.Registry) (*client.TCPClient, error) {
+	ia, err := newIA(flagName, spec)
+	if err != nil {
+		return nil, err
+	}
+	// The server is started in the background, it may not listen yet
+	player, err := client.NewTCPClient(serverAddr, ia.Name(), ia)
+	for start := time.Now(); err != nil && time.Since(start) < serverStartTimeout; {
+		time.Sleep(50 * time.Millisecond)
+		player, err = client.NewTCPClient(serverAddr, ia.Name(), ia)
+	}
+	if err != nil {
+		return nil, err
+	}
+	player.SetMetrics(client.NewPlayerMetrics(registry, "player", flagName))
+	if err = player.Init(); err != nil {
+		return nil, fmt.Errorf("fail to init %s: %s", flagName, err)
+	}
+	return &player, nil
+}
+
+func (c *serveCommand) run(args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return usageErrorf("unexpected arguments %v", args)
+	}
+	if err := c.maps.check(); err != nil {
+		return err
+	}
+	if c.p1 == "" && c.p2 == "" {
+		return usageErrorf("please specify the IA of at least one player with -p1 or -p2")
+	}
+	// Report the invalid specs before starting anything
+	for flagName, spec := range map[string]string{"p1": c.p1, "p2": c.p2} {
+		if spec == "" {
+			continue
+		}
+		if _, err := newIA(flagName, spec); err != nil {
+			return err
+		}
+	}
+
+	log.Print("starting server...")
+
+	// For profiling, the metrics of the players are served on /metrics
+	registry := metrics.NewRegistry()
+	if c.metricsAddr != "" {
+		http.Handle("/metrics", registry.Handler())
+		go func() {
+			log.Println(http.ListenAndServe(c.metricsAddr, nil))
+		}()
+	}
+
+	c.maps.startServer(time.Duration(c.timeoutS)*time.Second, false, nil, false, nil)
+
+	// The first player to connect is the werewolves
+	var players []*client.TCPClient
+	if c.p1 != "" {
+		player1, err := c.connect("p1", c.p1, registry)
+		if err != nil {
+			return err
+		}
+		players = append(players, player1)
+	} else {
+		time.Sleep(c.wait)
+	}
+	if c.p2 != "" {
+		player2, err := c.connect("p2", c.p2, registry)
+		if err != nil {
+			return err
+		}
+		players = append(players, player2)
+	}
+
+	for _, player := range players[1:] {
+		go player.Play()
+	}
+	players[0].Play()
+
+	time.Sleep(c.linger)
+	return nil
+}
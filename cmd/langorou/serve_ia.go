@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/langorou/langorou/pkg/remote"
+)
+
+type serveIACommand struct {
+	addr string
+	ia   string
+}
+
+func (c *serveIACommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", "localhost:8000", "address to listen on")
+	fs.StringVar(&c.ia, "ia", "minmax", specUsage("spec of the IA to serve"))
+}
+
+func (c *serveIACommand) run(args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return usageErrorf("unexpected arguments %v", args)
+	}
+	ia, err := newIA("ia", c.ia)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("serving %s on http://%s", ia.Name(), c.addr)
+	return http.ListenAndServe(c.addr, remote.NewHandler(ia))
+}
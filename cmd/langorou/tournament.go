@@ -2,6 +2,8 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
@@ -10,85 +12,141 @@ import (
 	"sync"
 	"time"
 
-	"github.com/langorou/langorou/pkg/utils"
-
-	_ "net/http/pprof"
-
 	"github.com/langorou/langorou/pkg/client"
 	"github.com/langorou/langorou/pkg/tournament"
+	"github.com/langorou/langorou/pkg/utils"
 )
 
-func failIf(err error, msg string) {
-	if err != nil {
-		log.Fatalf("error %s: %v", msg, err)
-	}
-}
-
 const nRandMaps = 1 // number of random maps to generate
 
-var mapPath string
-var mapFolder string
-var useRand bool
-var rows int
-var columns int
-var humans int
-var monster int
-var timeoutS int
+type tournamentCommand struct {
+	mapPath       string
+	mapFolder     string
+	timeoutS      int
+	seed          int64
+	specs         specList
+	dashboardAddr string
+	outDir        string
+	log           logFlags
+}
+
+func (c *tournamentCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.mapPath, "map", "", "path to the map to play on, random maps are generated if neither -map nor -mapFolder is given")
+	fs.StringVar(&c.mapFolder, "mapFolder", "", "path for the folder in which we can test several maps")
+	fs.IntVar(&c.timeoutS, "timeout", 8, "timeout in seconds for each move")
+	fs.Int64Var(&c.seed, "seed", 0, "seed of the random generator, based on the time if 0")
+	fs.Var(&c.specs, "ia", specUsage("spec of a participant, can be repeated to replace the default participants"))
+	fs.StringVar(&c.dashboardAddr, "dashboard", "", "address on which to serve the live dashboard, for instance :8081 (disabled if empty)")
+	fs.StringVar(&c.outDir, "out", "./out", "folder in which the results are saved")
+	c.log.register(fs, "warn")
+}
 
-func getMaps(root string) []string {
+func getMaps(root string) ([]string, error) {
 	var files []string
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		files = append(files, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, ".xml") {
+			files = append(files, path)
+		}
 		return nil
 	})
-	if err != nil {
-		panic(err)
-	}
 
-	return files
+	return files, err
 }
 
-func init() {
-	flag.StringVar(&mapPath, "map", "", "path to the map to load (or save if randomly generating)")
-	flag.StringVar(&mapFolder, "mapFolder", "", "path for the folder in which we can test several maps")
-	flag.BoolVar(&useRand, "rand", false, "use a randomly generated map")
-	flag.IntVar(&rows, "rows", 10, "total number of rows")
-	flag.IntVar(&columns, "columns", 10, "total number of columns")
-	flag.IntVar(&humans, "humans", 16, "quantity of humans group")
-	flag.IntVar(&monster, "monster", 8, "quantity of monster in the start case")
-	flag.IntVar(&timeoutS, "timeout", 8, "timeout in seconds for each move")
+// participants returns the participants of the -ia flags, the default ones if there are none
+func (c *tournamentCommand) participants() ([]tournament.Participant, error) {
+	if len(c.specs) == 0 {
+		return defaultParticipants(), nil
+	}
+
+	var participants []tournament.Participant
+	for _, spec := range c.specs {
+		// Catch the typos before playing any match
+		if _, err := newIA("ia", spec); err != nil {
+			return nil, err
+		}
+		participants = append(participants, tournament.Participant{Spec: spec})
+	}
+	return participants, nil
 }
 
-func main() {
-	rand.Seed(time.Now().UTC().UnixNano())
-	flag.Parse()
+func (c *tournamentCommand) run(args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return usageErrorf("unexpected arguments %v", args)
+	}
+	if c.mapPath != "" && c.mapFolder != "" {
+		return usageErrorf("please specify either -map or -mapFolder")
+	}
+	if err := c.log.setup(); err != nil {
+		return err
+	}
+
+	competitors, err := c.participants()
+	if err != nil {
+		return err
+	}
+	if len(competitors) < 2 {
+		return usageErrorf("a tournament needs at least 2 participants, got %d", len(competitors))
+	}
 
-	competitors := generatePlayers()
+	var mapPaths []string
+	switch {
+	case c.mapFolder != "":
+		if mapPaths, err = getMaps(c.mapFolder); err != nil {
+			return fmt.Errorf("listing the maps: %s", err)
+		}
+		if len(mapPaths) == 0 {
+			return fmt.Errorf("no map found in %s", c.mapFolder)
+		}
+	case c.mapPath != "":
+		mapPaths = []string{c.mapPath}
+	}
+
+	if c.seed == 0 {
+		c.seed = time.Now().UTC().UnixNano()
+	}
+	rand.Seed(c.seed)
+	log.Printf("Using seed %d", c.seed)
 
 	matchSummaryCh := make(chan tournament.MatchSummary)
 	var leaderboard tournament.Result
 
+	// The observer stays nil (and not a nil *Dashboard) when the dashboard is disabled
+	var observer tournament.MatchObserver
+	var dashboard *tournament.Dashboard
+	if c.dashboardAddr != "" {
+		dashboard = tournament.NewDashboard()
+		observer = dashboard
+		go func() {
+			log.Printf("Serving the dashboard on %s", c.dashboardAddr)
+			log.Fatalf("error serving the dashboard: %s", dashboard.Serve(c.dashboardAddr))
+		}()
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func(wg *sync.WaitGroup) {
 		for res := range matchSummaryCh {
+			res.Seed = c.seed
 			leaderboard = append(leaderboard, res)
+			if dashboard != nil {
+				dashboard.MatchFinished(res)
+			}
 		}
 		wg.Done()
 	}(&wg)
 
-	if mapFolder != "" {
+	if len(mapPaths) > 0 {
 		log.Printf("Using the maps provided for the tournament")
 
-		mapPaths := getMaps(mapFolder)
-
 		for _, mp := range mapPaths {
 			// could use go on this, but generate two many games at the same time
-			if strings.HasSuffix(mp, ".xml") {
-				log.Printf("Launching tournament on map %s", mp)
-				tournament.RunTournamentOnMap(mp, false, tournament.RandMapLimits{}, timeoutS, competitors, matchSummaryCh)
-			}
+			log.Printf("Launching tournament on map %s", mp)
+			tournament.RunTournamentOnMap(mp, false, tournament.RandMapLimits{}, c.timeoutS, competitors, matchSummaryCh, observer)
 		}
 	} else {
 		limits := tournament.RandMapLimits{
@@ -101,22 +159,25 @@ func main() {
 		}
 
 		for i := 0; i < nRandMaps; i++ {
-			tournament.RunTournamentOnMap("", true, limits, timeoutS, competitors, matchSummaryCh)
+			tournament.RunTournamentOnMap("", true, limits, c.timeoutS, competitors, matchSummaryCh, observer)
 		}
 	}
 	close(matchSummaryCh)
 	wg.Wait()
 
-	log.Printf("\nGames summary\n--------\n%s\n", leaderboard.MatchResults())
-	log.Printf("\nFinal Scores\n--------\n%s", leaderboard.Leaderboard())
-
-	failIf(utils.CreateDirIfNotExist("./out"), "")
-	failIf(leaderboard.Save("./out/"), "saving")
+	fmt.Fprintf(out, "Games summary\n--------\n%s\n", leaderboard.MatchResults())
+	fmt.Fprintf(out, "Final Scores\n--------\n%s", leaderboard.Leaderboard())
 
-	os.Exit(0)
+	if err = utils.CreateDirIfNotExist(c.outDir); err != nil {
+		return err
+	}
+	if err = leaderboard.Save(c.outDir); err != nil {
+		return fmt.Errorf("saving: %s", err)
+	}
+	return nil
 }
 
-func generatePlayers() []tournament.Participant {
+func defaultParticipants() []tournament.Participant {
 	dur := 1 * time.Second
 
 	players := []tournament.Participant{
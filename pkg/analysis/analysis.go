@@ -0,0 +1,294 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+// Options configures the analysis of a replay
+type Options struct {
+	// Timeout is the time budget used to search each position, and then to evaluate the played coup
+	Timeout time.Duration
+	// Depth, when not 0, replaces Timeout: every position is searched at this depth, the results then don't depend on
+	// the speed of the machine
+	Depth uint8
+	// Threshold is the loss of expected value above which a turn is flagged as a blunder
+	Threshold float64
+	// Players are the players to analyze
+	Players []tournament.Perspective
+	// Engine is the spec of the IA searching the positions, like "minmax:battles=0.02", it should be a min max one.
+	// The heuristic of each participant is used if it is empty
+	Engine string
+	// Explain adds to each turn the breakdown of the score of the position by the heuristic
+	Explain bool
+}
+
+// TurnReport is the analysis of one move of a match
+type TurnReport struct {
+	// Frame is the index in the history of the position resulting from the move
+	Frame  int
+	Turn   int
+	Side   tournament.Perspective
+	Player string
+	// Played is the coup inferred from the history, Exact is false if it had to be guessed
+	Played model.Coup
+	Exact  bool
+	// Best is the best coup found by the search, at depth Depth
+	Best  model.Coup
+	Depth uint8
+	// PlayedScore and BestScore are the expected scores of the played and best coups, Loss is the difference
+	PlayedScore float64
+	BestScore   float64
+	Loss        float64
+	Blunder     bool
+	// Explanation breaks down the score of the position before the move, when asked for
+	Explanation *client.Explanation `json:",omitempty"`
+}
+
+// Report is the analysis of a whole match
+type Report struct {
+	MapName   string
+	Player1   string
+	Player2   string
+	Timeout   time.Duration
+	Depth     uint8 `json:",omitempty"`
+	Threshold float64
+	Turns     []TurnReport
+}
+
+// heuristicFromSpec returns the heuristic of the IA created from spec
+func heuristicFromSpec(spec string) (client.Heuristic, error) {
+	ia, err := client.NewIA(spec)
+	if err != nil {
+		return client.Heuristic{}, err
+	}
+	s, ok := ia.(client.Searcher)
+	if !ok {
+		return client.Heuristic{}, fmt.Errorf("IA %s can't analyze positions, it has no heuristic", spec)
+	}
+	return s.Heuristic(), nil
+}
+
+// heuristicFor returns the heuristic used to analyze the moves of a participant: its own if it has one, else the
+// default one. An invalid spec is an error
+func heuristicFor(p tournament.Participant) (client.Heuristic, error) {
+	if p.Spec != "" {
+		ia, err := client.NewIA(p.Spec)
+		if err != nil {
+			return client.Heuristic{}, fmt.Errorf("participant %s: %s", p.Name(), err)
+		}
+		if s, ok := ia.(client.Searcher); ok {
+			return s.Heuristic(), nil
+		}
+		return client.NewHeuristic(client.NewDefaultHeuristicParameters()), nil
+	}
+	if p.Dumb {
+		return client.NewHeuristic(client.NewDefaultHeuristicParameters()), nil
+	}
+	return client.NewHeuristic(p.Params), nil
+}
+
+// mover finds which player moved between two consecutive frames, the capture of a group by the enemy doesn't count as
+// a move of its owner
+func mover(mr *tournament.MatchSummary, frame int) (tournament.Perspective, bool) {
+	persp, ok := mr.Mover(frame)
+	if !ok {
+		return persp, false
+	}
+	before, _ := mr.State(frame-1, persp)
+	after, _ := mr.State(frame, persp)
+	coup, _ := InferCoup(before, after)
+	return persp, len(coup) != 0
+}
+
+// Analyze rebuilds every position of the match, searches it again and compares the played move with the best one
+func Analyze(mr *tournament.MatchSummary, opts Options) (*Report, error) {
+	if len(mr.History) < 2 {
+		return nil, fmt.Errorf("replay has %d frames, nothing to analyze", len(mr.History))
+	}
+
+	report := &Report{
+		MapName:   mr.MapName,
+		Player1:   mr.Player1.Name(),
+		Player2:   mr.Player2.Name(),
+		Timeout:   opts.Timeout,
+		Depth:     opts.Depth,
+		Threshold: opts.Threshold,
+	}
+
+	analyzed := map[tournament.Perspective]bool{}
+	for _, persp := range opts.Players {
+		analyzed[persp] = true
+	}
+
+	heuristics := map[tournament.Perspective]client.Heuristic{}
+	if opts.Engine != "" {
+		h, err := heuristicFromSpec(opts.Engine)
+		if err != nil {
+			return nil, err
+		}
+		heuristics[tournament.Player1] = h
+		heuristics[tournament.Player2] = h
+	} else {
+		for _, persp := range []tournament.Perspective{tournament.Player1, tournament.Player2} {
+			h, err := heuristicFor(mr.Participant(persp))
+			if err != nil {
+				return nil, err
+			}
+			heuristics[persp] = h
+		}
+	}
+
+	for frame := 1; frame < len(mr.History); frame++ {
+		persp, ok := mover(mr, frame)
+		if !ok || !analyzed[persp] {
+			continue
+		}
+
+		before, _ := mr.State(frame-1, persp)
+		after, _ := mr.State(frame, persp)
+		played, exact := InferCoup(before, after)
+
+		h := heuristics[persp]
+		var best client.Evaluation
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if opts.Depth > 0 {
+			best = h.SearchDepth(before, opts.Depth)
+		} else {
+			best = h.SearchWithTimeout(before, opts.Timeout)
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		}
+		playedScore, depth := h.EvaluateCoup(ctx, before, played, best.Depth)
+		cancel()
+		if depth < best.Depth {
+			// The played coup could be way longer to search than the best one, like when the best one ends the game:
+			// compare them at the same depth
+			best.Score, best.Depth = h.EvaluateCoup(context.Background(), before, best.Coup, depth)
+		}
+
+		turn := TurnReport{
+			Frame:       frame,
+			Turn:        mr.History[frame].Mov,
+			Side:        persp,
+			Player:      mr.Participant(persp).Name(),
+			Played:      played,
+			Exact:       exact,
+			Best:        best.Coup,
+			Depth:       best.Depth,
+			PlayedScore: playedScore,
+			BestScore:   best.Score,
+			Loss:        best.Score - playedScore,
+		}
+		turn.Blunder = turn.Loss > opts.Threshold
+		if opts.Explain {
+			e := h.Explain(before)
+			turn.Explanation = &e
+		}
+		report.Turns = append(report.Turns, turn)
+	}
+
+	return report, nil
+}
+
+// Blunders returns the turns flagged as blunders
+func (r *Report) Blunders() []TurnReport {
+	var blunders []TurnReport
+	for _, t := range r.Turns {
+		if t.Blunder {
+			blunders = append(blunders, t)
+		}
+	}
+	return blunders
+}
+
+func formatCoup(coup model.Coup) string {
+	if len(coup) == 0 {
+		return "-"
+	}
+
+	moves := make([]string, len(coup))
+	for i, m := range coup {
+		moves[i] = fmt.Sprintf("%d,%d>%d>%d,%d", m.Start.X, m.Start.Y, m.N, m.End.X, m.End.Y)
+	}
+	return strings.Join(moves, " ")
+}
+
+// WriteText writes a human readable report, blunders are highlighted with "!!"
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s (P1) VS (P2) %s on %s\n", r.Player1, r.Player2, r.MapName)
+	if r.Depth > 0 {
+		fmt.Fprintf(&b, "search depth: %d, blunder threshold: %.2f\n\n", r.Depth, r.Threshold)
+	} else {
+		fmt.Fprintf(&b, "search timeout: %s, blunder threshold: %.2f\n\n", r.Timeout, r.Threshold)
+	}
+	fmt.Fprintf(&b, "   %4s %2s %5s %-28s %-28s %12s %12s %12s\n", "turn", "", "depth", "played", "best", "played score", "best score", "loss")
+
+	for _, t := range r.Turns {
+		flag := "  "
+		if t.Blunder {
+			flag = "!!"
+		}
+		played := formatCoup(t.Played)
+		if !t.Exact {
+			played += "?"
+		}
+		fmt.Fprintf(
+			&b, "%s %4d %2s %5d %-28s %-28s %12.2f %12.2f %12.2f\n",
+			flag, t.Turn, t.Side, t.Depth, played, formatCoup(t.Best), t.PlayedScore, t.BestScore, t.Loss,
+		)
+		if t.Explanation != nil {
+			writeExplanation(&b, t.Explanation)
+		}
+	}
+
+	blunders := r.Blunders()
+	fmt.Fprintf(&b, "\n%d blunders\n", len(blunders))
+	for _, t := range blunders {
+		fmt.Fprintf(&b, "turn %d (%s, %s): lost %.2f, played %s instead of %s\n", t.Turn, t.Side, t.Player, t.Loss, formatCoup(t.Played), formatCoup(t.Best))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var raceNames = map[model.Race]string{model.Neutral: "humans", model.Ally: "ally", model.Enemy: "enemy"}
+
+// writeExplanation writes the terms of an explanation from the largest contribution, then the battles considered
+// from each monster group
+func writeExplanation(b *strings.Builder, e *client.Explanation) {
+	fmt.Fprintf(b, "        score %.2f", e.Score)
+	if e.Outcome != "" {
+		fmt.Fprintf(b, " (%s)", e.Outcome)
+	}
+	fmt.Fprintf(b, "\n        %-16s %12s %12s %12s %10s %12s\n", "term", "ally", "enemy", "value", "coef", "contribution")
+	for _, t := range e.Largest() {
+		fmt.Fprintf(b, "        %-16s %12.2f %12.2f %12.2f %10.4f %12.2f\n", t.Name, t.Ally, t.Enemy, t.Value, t.Coef, t.Contribution)
+	}
+	for _, c := range e.Cells {
+		fmt.Fprintf(b, "        %s %d at %d,%d:", raceNames[c.Cell.Race], c.Cell.Count, c.Coords.X, c.Coords.Y)
+		for _, p := range c.Battles {
+			fmt.Fprintf(b, " %s %d at %d,%d %+.2f", raceNames[p.Cell.Race], p.Cell.Count, p.Target.X, p.Target.Y, p.Gain)
+			if !p.Neutral {
+				fmt.Fprintf(b, "/%+.2f", p.Loss)
+			}
+		}
+		b.WriteString("\n")
+	}
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
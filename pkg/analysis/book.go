@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+// winner returns the player who won a match, ok is false for a tie
+func winner(mr *tournament.MatchSummary) (persp tournament.Perspective, ok bool) {
+	switch {
+	case mr.Player1Eff > mr.Player2Eff:
+		return tournament.Player1, true
+	case mr.Player2Eff > mr.Player1Eff:
+		return tournament.Player2, true
+	default:
+		return tournament.Player1, false
+	}
+}
+
+// AddReplayToBook adds the first plies coups of the winner of a match to an opening book, with a weight of 1 each.
+// Ties are ignored, like the coups which can't be inferred exactly from the history. It returns the number of coups
+// added
+func AddReplayToBook(b *client.Book, mr *tournament.MatchSummary, plies int) int {
+	persp, ok := winner(mr)
+	if !ok {
+		return 0
+	}
+
+	var played, added int
+	for frame := 1; frame < len(mr.History) && played < plies; frame++ {
+		if p, ok := mover(mr, frame); !ok || p != persp {
+			continue
+		}
+		played++
+
+		before, _ := mr.State(frame-1, persp)
+		after, _ := mr.State(frame, persp)
+		coup, exact := InferCoup(before, after)
+		if !exact {
+			continue
+		}
+		b.Add(before, coup, 1)
+		added++
+	}
+	return added
+}
+
+// AddSearchToBook searches the best coup of the Ally race in a state during timeout and adds it to an opening book
+// with the given weight, the search is returned
+func AddSearchToBook(b *client.Book, s *model.State, h client.Heuristic, timeout time.Duration, weight float64) client.Evaluation {
+	eval := h.SearchWithTimeout(s.Copy(false), timeout)
+	if len(eval.Coup) > 0 {
+		b.Add(s, eval.Coup, weight)
+	}
+	return eval
+}
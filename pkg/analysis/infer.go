@@ -0,0 +1,183 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// source is a cell from which Ally units left during a coup
+type source struct {
+	coords model.Coordinates
+	left   uint8
+}
+
+// destination is a cell that changed during a coup without being a source
+type destination struct {
+	coords model.Coordinates
+	// arrived is the number of units that arrived, only known if no battle took place
+	arrived uint8
+	battle  bool
+}
+
+func allyCount(s *model.State, c model.Coordinates) uint8 {
+	if cell, ok := s.Grid[c]; ok && cell.Race == model.Ally {
+		return cell.Count
+	}
+	return 0
+}
+
+func isNeighbour(c1, c2 model.Coordinates) bool {
+	return c1 != c2 && c1.Distance(c2) <= 1
+}
+
+// InferCoup reconstructs the coup played by the Ally race between two consecutive states, only the Ally
+// race should have moved in between. Since battles are random, the number of units sent into a battle can
+// only be deduced from the cells they left, when it is ambiguous the units are split evenly and exact is false
+func InferCoup(before, after *model.State) (coup model.Coup, exact bool) {
+	exact = true
+
+	var sources []source
+	for c, cell := range before.Grid {
+		if cell.Race != model.Ally {
+			continue
+		}
+		if remaining := allyCount(after, c); remaining < cell.Count {
+			sources = append(sources, source{coords: c, left: cell.Count - remaining})
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, true
+	}
+
+	isSource := map[model.Coordinates]bool{}
+	for _, src := range sources {
+		isSource[src.coords] = true
+	}
+
+	var destinations []destination
+	seen := map[model.Coordinates]bool{}
+	for _, grid := range []map[model.Coordinates]model.Cell{before.Grid, after.Grid} {
+		for c := range grid {
+			if seen[c] || isSource[c] {
+				continue
+			}
+			seen[c] = true
+
+			b, a := before.Grid[c], after.Grid[c]
+			if b.IsEmpty() && a.IsEmpty() || b == a {
+				continue
+			}
+
+			if b.IsEmpty() || b.Race == model.Ally {
+				if a.Race != model.Ally || a.Count < b.Count {
+					// A cell left by the Ally race is a source, so units of another race appeared on an empty cell:
+					// they moved too and the coup can't be fully explained
+					exact = false
+					continue
+				}
+				destinations = append(destinations, destination{coords: c, arrived: a.Count - b.Count})
+			} else {
+				destinations = append(destinations, destination{coords: c, battle: true})
+			}
+		}
+	}
+
+	// Iterate in a deterministic order
+	sort.Slice(sources, func(i, j int) bool { return model.LessCoordinates(sources[i].coords, sources[j].coords) })
+	sort.Slice(destinations, func(i, j int) bool {
+		return model.LessCoordinates(destinations[i].coords, destinations[j].coords)
+	})
+
+	moves := map[[2]model.Coordinates]uint8{}
+	send := func(src *source, dst model.Coordinates, n uint8) {
+		if n == 0 {
+			return
+		}
+		src.left -= n
+		moves[[2]model.Coordinates{src.coords, dst}] += n
+	}
+
+	// First the destinations where no battle occurred, we know exactly how many units arrived
+	for _, dst := range destinations {
+		if dst.battle {
+			continue
+		}
+
+		var candidates []*source
+		for i := range sources {
+			if isNeighbour(sources[i].coords, dst.coords) && sources[i].left > 0 {
+				candidates = append(candidates, &sources[i])
+			}
+		}
+		if len(candidates) > 1 {
+			exact = false
+		}
+
+		needed := dst.arrived
+		for _, src := range candidates {
+			n := needed
+			if src.left < n {
+				n = src.left
+			}
+			send(src, dst.coords, n)
+			needed -= n
+		}
+		if needed > 0 {
+			exact = false
+		}
+	}
+
+	// Then the battles, they take all the units left from the neighbouring sources
+	for i := range sources {
+		src := &sources[i]
+		if src.left == 0 {
+			continue
+		}
+
+		var battles []model.Coordinates
+		for _, dst := range destinations {
+			if dst.battle && isNeighbour(src.coords, dst.coords) {
+				battles = append(battles, dst.coords)
+			}
+		}
+
+		if len(battles) == 0 {
+			// The units were lost in a battle that left the defenders untouched, find where
+			for c, cell := range before.Grid {
+				if cell.Race != model.Ally && !cell.IsEmpty() && isNeighbour(src.coords, c) && before.Grid[c] == after.Grid[c] {
+					battles = append(battles, c)
+				}
+			}
+			sort.Slice(battles, func(i, j int) bool { return model.LessCoordinates(battles[i], battles[j]) })
+		}
+
+		if len(battles) != 1 {
+			exact = false
+		}
+		if len(battles) == 0 {
+			continue
+		}
+
+		share := src.left / uint8(len(battles))
+		for j, dst := range battles {
+			if j == len(battles)-1 {
+				share = src.left
+			}
+			send(src, dst, share)
+		}
+	}
+
+	for key, n := range moves {
+		coup = append(coup, model.Move{Start: key[0], N: n, End: key[1]})
+	}
+	sort.Slice(coup, func(i, j int) bool {
+		if coup[i].Start != coup[j].Start {
+			return model.LessCoordinates(coup[i].Start, coup[j].Start)
+		}
+		return model.LessCoordinates(coup[i].End, coup[j].End)
+	})
+
+	return coup, exact
+}
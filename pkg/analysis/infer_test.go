@@ -0,0 +1,260 @@
+package analysis
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/tournament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferCoup(t *testing.T) {
+	t.Run("simple move", func(t *testing.T) {
+		before := model.NewState(3, 3)
+		before.SetCell(model.Coordinates{}, model.Ally, 10)
+		before.SetCell(model.Coordinates{X: 2, Y: 2}, model.Enemy, 10)
+
+		after := before.Copy(false)
+		after.EmptyCell(model.Coordinates{})
+		after.SetCell(model.Coordinates{X: 1, Y: 1}, model.Ally, 10)
+
+		coup, exact := InferCoup(before, after)
+		assert.True(t, exact)
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{}, N: 10, End: model.Coordinates{X: 1, Y: 1}}}, coup)
+	})
+
+	t.Run("split and battle", func(t *testing.T) {
+		// 10A | 04N
+		// XXX | XXX
+		before := model.NewState(2, 2)
+		before.SetCell(model.Coordinates{}, model.Ally, 10)
+		before.SetCell(model.Coordinates{X: 1}, model.Neutral, 4)
+		before.SetCell(model.Coordinates{X: 1, Y: 1}, model.Enemy, 3)
+
+		// 4 units stayed, 2 went down, 4 took the humans
+		after := before.Copy(false)
+		after.SetCell(model.Coordinates{}, model.Ally, 4)
+		after.SetCell(model.Coordinates{Y: 1}, model.Ally, 2)
+		after.SetCell(model.Coordinates{X: 1}, model.Ally, 8)
+
+		coup, exact := InferCoup(before, after)
+		assert.True(t, exact)
+		assert.Equal(t, model.Coup{
+			{Start: model.Coordinates{}, N: 4, End: model.Coordinates{X: 1}},
+			{Start: model.Coordinates{}, N: 2, End: model.Coordinates{Y: 1}},
+		}, coup)
+	})
+
+	t.Run("lost battle without casualties", func(t *testing.T) {
+		before := model.NewState(3, 3)
+		before.SetCell(model.Coordinates{}, model.Ally, 2)
+		before.SetCell(model.Coordinates{X: 1}, model.Neutral, 4)
+		before.SetCell(model.Coordinates{X: 2, Y: 2}, model.Enemy, 3)
+
+		after := before.Copy(false)
+		after.EmptyCell(model.Coordinates{})
+
+		coup, exact := InferCoup(before, after)
+		assert.True(t, exact)
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{}, N: 2, End: model.Coordinates{X: 1}}}, coup)
+	})
+
+	t.Run("enemy moved", func(t *testing.T) {
+		before := model.GenerateSimpleState()
+		after := before.Copy(false)
+		after.EmptyCell(model.Coordinates{Y: 4})
+		after.SetCell(model.Coordinates{Y: 3}, model.Enemy, 68)
+
+		coup, _ := InferCoup(before, after)
+		assert.Empty(t, coup)
+	})
+
+	t.Run("both races moved", func(t *testing.T) {
+		before := model.NewState(3, 3)
+		before.SetCell(model.Coordinates{}, model.Ally, 10)
+		before.SetCell(model.Coordinates{X: 2, Y: 2}, model.Enemy, 10)
+
+		after := before.Copy(false)
+		after.EmptyCell(model.Coordinates{})
+		after.SetCell(model.Coordinates{X: 1}, model.Ally, 10)
+		after.EmptyCell(model.Coordinates{X: 2, Y: 2})
+		after.SetCell(model.Coordinates{X: 2, Y: 1}, model.Enemy, 10)
+
+		coup, exact := InferCoup(before, after)
+		assert.False(t, exact)
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{}, N: 10, End: model.Coordinates{X: 1}}}, coup)
+	})
+}
+
+const testReplay = `{
+	"MapName": "test",
+	"Player1": {"Dumb": true},
+	"Player2": {"Dumb": true},
+	"History": [
+		{"X": 241, "Y": 241, "Humans": [{"c": 4, "X": 80, "Y": 80}], "Wolfs": [{"c": 6, "X": 0, "Y": 0}], "Vamps": [{"c": 6, "X": 160, "Y": 160}], "Mov": 0},
+		{"X": 241, "Y": 241, "Humans": [{"c": 4, "X": 80, "Y": 80}], "Wolfs": [{"c": 6, "X": 0, "Y": 80}], "Vamps": [{"c": 6, "X": 160, "Y": 160}], "Mov": 1},
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 6, "X": 0, "Y": 80}], "Vamps": [{"c": 10, "X": 80, "Y": 80}], "Mov": 2}
+	]
+}`
+
+// captureReplay is a match where the vampires take the werewolves
+const captureReplay = `{
+	"MapName": "capture",
+	"Player1": {"Dumb": true},
+	"Player2": {"Dumb": true},
+	"History": [
+		{"X": 241, "Y": 241, "Humans": [{"c": 2, "X": 160, "Y": 160}], "Wolfs": [{"c": 5, "X": 0, "Y": 0}], "Vamps": [{"c": 10, "X": 80, "Y": 80}], "Mov": 0},
+		{"X": 241, "Y": 241, "Humans": [{"c": 2, "X": 160, "Y": 160}], "Wolfs": [{"c": 5, "X": 0, "Y": 80}], "Vamps": [{"c": 10, "X": 80, "Y": 80}], "Mov": 1},
+		{"X": 241, "Y": 241, "Humans": [{"c": 2, "X": 160, "Y": 160}], "Wolfs": [], "Vamps": [{"c": 10, "X": 0, "Y": 80}], "Mov": 2}
+	]
+}`
+
+// analysisDepth is deep enough to see the humans taken, the reports of the tests then don't depend on the machine
+const analysisDepth = 3
+
+func loadTestReplay(t *testing.T, replay string) *tournament.MatchSummary {
+	var mr tournament.MatchSummary
+	require.NoError(t, json.Unmarshal([]byte(replay), &mr))
+	return &mr
+}
+
+func TestAnalyzeBlunders(t *testing.T) {
+	report, err := Analyze(loadTestReplay(t, testReplay), Options{
+		Depth:     analysisDepth,
+		Threshold: 1,
+		Players:   []tournament.Perspective{tournament.Player1, tournament.Player2},
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Turns, 2)
+
+	// The werewolves went away from the humans instead of taking them
+	assert.Equal(t, tournament.Player1, report.Turns[0].Side)
+	assert.Equal(t, model.Coup{{Start: model.Coordinates{}, N: 6, End: model.Coordinates{Y: 1}}}, report.Turns[0].Played)
+	assert.Equal(t, uint8(analysisDepth), report.Turns[0].Depth)
+	assert.True(t, report.Turns[0].Blunder)
+
+	// The vampires took the humans
+	assert.Equal(t, tournament.Player2, report.Turns[1].Side)
+	assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 2, Y: 2}, N: 6, End: model.Coordinates{X: 1, Y: 1}}}, report.Turns[1].Played)
+	assert.False(t, report.Turns[1].Blunder)
+
+	var b strings.Builder
+	require.NoError(t, report.WriteText(&b))
+	assert.Contains(t, b.String(), "search depth: 3")
+	assert.Nil(t, report.Turns[0].Explanation)
+}
+
+func TestAnalyzeEngine(t *testing.T) {
+	mr := loadTestReplay(t, testReplay)
+	report, err := Analyze(mr, Options{Depth: analysisDepth, Threshold: 1, Players: []tournament.Perspective{tournament.Player1}, Engine: "minmax:battles=0.5"})
+	require.NoError(t, err)
+	require.Len(t, report.Turns, 1)
+	assert.True(t, report.Turns[0].Blunder)
+
+	_, err = Analyze(mr, Options{Depth: analysisDepth, Engine: "dumb"})
+	assert.EqualError(t, err, "IA dumb can't analyze positions, it has no heuristic")
+
+	// The participants are analyzed with their own heuristic, an invalid spec isn't replaced by the default one
+	mr.Player2 = tournament.Participant{Spec: "minmax:depth=3"}
+	_, err = Analyze(mr, Options{Depth: analysisDepth, Players: []tournament.Perspective{tournament.Player1}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown options depth")
+}
+
+func TestAnalyzeExplain(t *testing.T) {
+	report, err := Analyze(loadTestReplay(t, testReplay), Options{Depth: 1, Players: []tournament.Perspective{tournament.Player1}, Explain: true})
+	require.NoError(t, err)
+	require.Len(t, report.Turns, 1)
+	e := report.Turns[0].Explanation
+	require.NotNil(t, e)
+	// The first position, 6 werewolves and 6 vampires with 4 humans in between
+	counts, ok := e.Term(client.TermCounts)
+	require.True(t, ok)
+	assert.Equal(t, 0., counts.Value)
+	require.Len(t, e.Cells, 2)
+	assert.Len(t, e.Cells[0].Battles, 2)
+
+	var b strings.Builder
+	require.NoError(t, report.WriteText(&b))
+	assert.Contains(t, b.String(), "neutral_battles")
+	assert.Contains(t, b.String(), "ally 6 at 0,0: humans 4 at 1,1")
+}
+
+func TestAnalyzeCapture(t *testing.T) {
+	// The werewolves taken by the vampires didn't move
+	report, err := Analyze(loadTestReplay(t, captureReplay), Options{
+		Depth:   1,
+		Players: []tournament.Perspective{tournament.Player1, tournament.Player2},
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Turns, 2)
+	assert.Equal(t, tournament.Player1, report.Turns[0].Side)
+	assert.Equal(t, tournament.Player2, report.Turns[1].Side)
+	assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 1, Y: 1}, N: 10, End: model.Coordinates{Y: 1}}}, report.Turns[1].Played)
+}
+
+func TestAnalyzeTimeout(t *testing.T) {
+	// Searched during the timeout, only the moves are checked since the depth depends on the machine
+	report, err := Analyze(loadTestReplay(t, testReplay), Options{Timeout: 50 * time.Millisecond, Players: []tournament.Perspective{tournament.Player2}})
+	require.NoError(t, err)
+	require.Len(t, report.Turns, 1)
+	assert.NotZero(t, report.Turns[0].Depth)
+	assert.NotEmpty(t, report.Turns[0].Best)
+}
+
+func TestAddReplayToBook(t *testing.T) {
+	var mr tournament.MatchSummary
+	require.NoError(t, json.Unmarshal([]byte(testReplay), &mr))
+
+	// A tie teaches nothing
+	b := client.NewBook()
+	assert.Equal(t, 0, AddReplayToBook(b, &mr, 4))
+	assert.Equal(t, 0, b.Len())
+
+	// The vampires won by taking the humans
+	mr.Player2Eff = 10
+	assert.Equal(t, 1, AddReplayToBook(b, &mr, 4))
+	before, _ := mr.State(1, tournament.Player2)
+	coup, ok := b.Choose(before, rand.New(rand.NewSource(1)))
+	require.True(t, ok)
+	assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 2, Y: 2}, N: 6, End: model.Coordinates{X: 1, Y: 1}}}, coup)
+
+	assert.Equal(t, 0, AddReplayToBook(client.NewBook(), &mr, 0))
+
+	// The werewolves lost, being taken by the vampires isn't a coup of theirs
+	var capture tournament.MatchSummary
+	require.NoError(t, json.Unmarshal([]byte(captureReplay), &capture))
+	capture.Player2Eff = 10
+	b = client.NewBook()
+	assert.Equal(t, 1, AddReplayToBook(b, &capture, 4))
+	before, _ = capture.State(1, tournament.Player2)
+	assert.Equal(t, []client.BookMove{{Coup: model.Coup{{Start: model.Coordinates{X: 1, Y: 1}, N: 10, End: model.Coordinates{Y: 1}}}, Weight: 1}}, b.Moves(before))
+	for frame := range capture.History {
+		s, _ := capture.State(frame, tournament.Player1)
+		assert.Empty(t, b.Moves(s), "frame %d", frame)
+	}
+
+	// Even credited with the win, the werewolves only played their first coup
+	capture.Player1Eff, capture.Player2Eff = 10, 0
+	b = client.NewBook()
+	assert.Equal(t, 1, AddReplayToBook(b, &capture, 4))
+	before, _ = capture.State(0, tournament.Player1)
+	assert.Len(t, b.Moves(before), 1)
+	before, _ = capture.State(1, tournament.Player1)
+	assert.Empty(t, b.Moves(before))
+
+	// A search adds its best coup
+	b = client.NewBook()
+	s := model.GenerateSimpleState()
+	eval := AddSearchToBook(b, s, client.NewHeuristic(client.NewDefaultHeuristicParameters()), 50*time.Millisecond, 2)
+	require.NotEmpty(t, eval.Coup)
+	moves := b.Moves(s)
+	require.Len(t, moves, 1)
+	assert.Equal(t, 2., moves[0].Weight)
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// BookMove is a coup of an opening book, the coups of a position are chosen at random in proportion to their weight
+type BookMove struct {
+	Coup   model.Coup `json:"coup"`
+	Weight float64    `json:"weight"`
+}
+
+// bookPosition is a position of the book as saved on disk
+type bookPosition struct {
+	// Hash is the hash of the state for the Ally race, written as a string since it doesn't fit in a JSON number
+	Hash  uint64     `json:"hash,string"`
+	Moves []BookMove `json:"moves"`
+}
+
+// Book is an opening book: the coups to play in known positions, looked up by State.Hash for the Ally race. It
+// should be filled before being shared between IAs
+type Book struct {
+	positions map[uint64][]BookMove
+}
+
+// NewBook creates an empty opening book
+func NewBook() *Book {
+	return &Book{positions: map[uint64][]BookMove{}}
+}
+
+// LoadBook reads a book saved as JSON
+func LoadBook(path string) (*Book, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var positions []bookPosition
+	if err = json.Unmarshal(b, &positions); err != nil {
+		return nil, fmt.Errorf("invalid book %s: %s", path, err)
+	}
+
+	book := NewBook()
+	for _, p := range positions {
+		for _, m := range p.Moves {
+			if len(m.Coup) == 0 || m.Weight <= 0 {
+				return nil, fmt.Errorf("invalid book %s: position %d has a move %v with weight %g", path, p.Hash, m.Coup, m.Weight)
+			}
+			book.add(p.Hash, m.Coup, m.Weight)
+		}
+	}
+	return book, nil
+}
+
+// Save writes the book as JSON, sorted by hash
+func (b *Book) Save(path string) error {
+	positions := make([]bookPosition, 0, len(b.positions))
+	for hash, moves := range b.positions {
+		positions = append(positions, bookPosition{Hash: hash, Moves: moves})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Hash < positions[j].Hash })
+
+	raw, err := json.MarshalIndent(positions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(raw, '\n'), 0644)
+}
+
+// Len returns the number of positions in the book
+func (b *Book) Len() int {
+	return len(b.positions)
+}
+
+// Add adds weight to a coup of the Ally race in a state, the same coup added twice sums its weights
+func (b *Book) Add(s *model.State, coup model.Coup, weight float64) {
+	b.add(s.Hash(model.Ally, nil), coup, weight)
+}
+
+func (b *Book) add(hash uint64, coup model.Coup, weight float64) {
+	// Compare the coups regardless of the order of their moves, Coup.Less isn't a total order
+	coup = append(model.Coup{}, coup...)
+	sort.Slice(coup, func(i, j int) bool {
+		switch {
+		case coup[i].Start != coup[j].Start:
+			return model.LessCoordinates(coup[i].Start, coup[j].Start)
+		case coup[i].End != coup[j].End:
+			return model.LessCoordinates(coup[i].End, coup[j].End)
+		default:
+			return coup[i].N < coup[j].N
+		}
+	})
+
+	moves := b.positions[hash]
+	for i, m := range moves {
+		if sameCoup(m.Coup, coup) {
+			moves[i].Weight += weight
+			return
+		}
+	}
+	b.positions[hash] = append(moves, BookMove{Coup: coup, Weight: weight})
+}
+
+func sameCoup(c1, c2 model.Coup) bool {
+	if len(c1) != len(c2) {
+		return false
+	}
+	for i := range c1 {
+		if c1[i] != c2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Moves returns the moves of the book in a state, with the heaviest first
+func (b *Book) Moves(s *model.State) []BookMove {
+	moves := append([]BookMove{}, b.positions[s.Hash(model.Ally, nil)]...)
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Weight > moves[j].Weight })
+	return moves
+}
+
+// Choose picks a coup of the book for the Ally race at random in proportion to the weights. The coups breaking the
+// rules in the state, after a collision of the hashes, are ignored. ok is false if the book has no coup to play
+func (b *Book) Choose(s *model.State, r *rand.Rand) (coup model.Coup, ok bool) {
+	var moves []BookMove
+	var total float64
+	for _, m := range b.positions[s.Hash(model.Ally, nil)] {
+		if model.ValidateCoup(s, model.Ally, m.Coup) == nil {
+			moves = append(moves, m)
+			total += m.Weight
+		}
+	}
+	if len(moves) == 0 {
+		return nil, false
+	}
+
+	x := r.Float64() * total
+	for _, m := range moves {
+		if x < m.Weight {
+			return append(model.Coup{}, m.Coup...), true
+		}
+		x -= m.Weight
+	}
+	// Rounding errors
+	return append(model.Coup{}, moves[len(moves)-1].Coup...), true
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBook(t *testing.T) {
+	s := model.GenerateSimpleState()
+	down := model.Coup{{Start: model.Coordinates{}, N: 68, End: model.Coordinates{Y: 1}}}
+	right := model.Coup{{Start: model.Coordinates{}, N: 68, End: model.Coordinates{X: 1}}}
+	split := model.Coup{
+		{Start: model.Coordinates{}, N: 34, End: model.Coordinates{Y: 1}},
+		{Start: model.Coordinates{}, N: 34, End: model.Coordinates{X: 1}},
+	}
+	require.NoError(t, model.ValidateCoup(s, model.Ally, split))
+
+	b := NewBook()
+	_, ok := b.Choose(s, rand.New(rand.NewSource(1)))
+	assert.False(t, ok)
+
+	b.Add(s, down, 1)
+	b.Add(s, right, 3)
+	b.Add(s, split, 1)
+	// The order of the moves doesn't matter
+	b.Add(s, model.Coup{split[1], split[0]}, 1)
+	// Another position
+	b.Add(model.GenerateComplicatedState(), down, 1)
+	assert.Equal(t, 2, b.Len())
+
+	moves := b.Moves(s)
+	require.Len(t, moves, 3)
+	assert.Equal(t, BookMove{Coup: right, Weight: 3}, moves[0])
+	assert.Equal(t, 2., moves[1].Weight)
+	assert.Equal(t, BookMove{Coup: down, Weight: 1}, moves[2])
+
+	// The coups are chosen in proportion to their weights
+	r := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 6000; i++ {
+		coup, ok := b.Choose(s, r)
+		require.True(t, ok)
+		switch {
+		case len(coup) == 2:
+			counts["split"]++
+		case coup[0].End.X == 1:
+			counts["right"]++
+		default:
+			counts["down"]++
+		}
+	}
+	assert.InDelta(t, 3000, counts["right"], 200)
+	assert.InDelta(t, 2000, counts["split"], 200)
+	assert.InDelta(t, 1000, counts["down"], 200)
+
+	// A coup breaking the rules, after a collision, is never chosen
+	invalid := NewBook()
+	invalid.Add(s, model.Coup{{Start: model.Coordinates{X: 9, Y: 9}, N: 4, End: model.Coordinates{X: 9, Y: 8}}}, 10)
+	_, ok = invalid.Choose(s, r)
+	assert.False(t, ok)
+}
+
+func TestSaveLoadBook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "langorou")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "book.json")
+
+	s := model.GenerateSimpleState()
+	coup := model.Coup{{Start: model.Coordinates{}, N: 68, End: model.Coordinates{Y: 1}}}
+	b := NewBook()
+	b.Add(s, coup, 2)
+	b.Add(model.GenerateComplicatedState(), coup, 1)
+	require.NoError(t, b.Save(path))
+
+	loaded, err := LoadBook(path)
+	require.NoError(t, err)
+	assert.Equal(t, b, loaded)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"hash": "1", "moves": [{"coup": [], "weight": 1}]}]`), 0644))
+	_, err = LoadBook(path)
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{}`), 0644))
+	_, err = LoadBook(path)
+	assert.Error(t, err)
+}
+
+func TestMinMaxIABook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "langorou")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "book.json")
+
+	s := model.GenerateSimpleState()
+	coup := model.Coup{{Start: model.Coordinates{}, N: 68, End: model.Coordinates{X: 1}}}
+	b := NewBook()
+	b.Add(s, coup, 1)
+	require.NoError(t, b.Save(path))
+
+	// Way longer than the test if it searched
+	ia, err := NewIA("minmax:timeout=10s,book=" + path)
+	require.NoError(t, err)
+	start := time.Now()
+	assert.Equal(t, coup, ia.Play(s))
+
+	var progress []model.Coup
+	assert.Equal(t, coup, ia.(Anytime).PlayAnytime(s, func(c model.Coup) { progress = append(progress, c) }))
+	assert.Equal(t, []model.Coup{coup}, progress)
+	assert.WithinDuration(t, start, time.Now(), time.Second)
+
+	// Out of the book, it searches
+	m := NewMinMaxIA(50 * time.Millisecond)
+	m.SetBook(b)
+	assert.NotEmpty(t, m.Play(model.GenerateComplicatedState()))
+
+	_, err = NewIA("minmax:book=" + filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -12,6 +12,12 @@ type DumbIA struct {
 
 var _ IA = &DumbIA{}
 
+func init() {
+	Register("dumb", "plays a random coup, no options", func(opts *Options) (IA, error) {
+		return NewDumbIA(), nil
+	})
+}
+
 func NewDumbIA() *DumbIA {
 	h := NewHeuristic(NewDefaultHeuristicParameters())
 	return &DumbIA{h}
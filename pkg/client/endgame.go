@@ -0,0 +1,454 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// Default limits of the positions solved exactly by the endgame solver, it is disabled by default since it rarely
+// solves an endgame on an open map and its time is then lost for the search
+const (
+	DefaultEndgameGroups = 0
+	DefaultEndgameUnits  = 24
+	DefaultEndgamePlies  = 10
+)
+
+// certain is the tolerance of the sums of probabilities considered equal to 1
+const certain = 1 - 1e-9
+
+// EndgameLimits bounds the positions solved exactly by the endgame solver
+type EndgameLimits struct {
+	// Groups is the maximum number of groups, humans included, 0 disables the solver
+	Groups int
+	// Units is the maximum number of units, humans included
+	Units int
+	// Plies is the maximum number of coups of both sides searched
+	Plies uint8
+}
+
+// NewDefaultEndgameLimits returns the limits of the endgames solved by default, with no groups the solver is disabled
+func NewDefaultEndgameLimits() EndgameLimits {
+	return EndgameLimits{Groups: DefaultEndgameGroups, Units: DefaultEndgameUnits, Plies: DefaultEndgamePlies}
+}
+
+// Contains tells if a state is small enough to be solved, the units of the state must also fit in a cell so that no
+// battle overflows
+func (l EndgameLimits) Contains(s *model.State) bool {
+	if l.Groups <= 0 || l.Plies == 0 || s.GameOver() {
+		return false
+	}
+	var groups, units int
+	for _, cell := range s.Grid {
+		if cell.IsEmpty() {
+			continue
+		}
+		groups++
+		units += int(cell.Count)
+	}
+	return groups <= l.Groups && units <= l.Units && units <= math.MaxUint8
+}
+
+// InReach tells if the Ally race can reach every enemy group within Plies coups. Otherwise an enemy group can stay
+// away and the solver can't find a forced win, it would only waste its time
+func (l EndgameLimits) InReach(s *model.State) bool {
+	// The Ally race plays the odd coups
+	reach := float64((int(l.Plies) + 1) / 2)
+	for c1, cell1 := range s.Grid {
+		if cell1.Race != model.Enemy {
+			continue
+		}
+		reached := false
+		for c2, cell2 := range s.Grid {
+			if cell2.Race == model.Ally && c1.Distance(c2) <= reach {
+				reached = true
+				break
+			}
+		}
+		if !reached {
+			return false
+		}
+	}
+	return true
+}
+
+// EndgameResult is the outcome of an endgame for the Ally race when both sides play their best. Unlike the simulator
+// used by the search, the solver follows the rules of the server: every coup, split included, and every number of
+// survivors of the random battles, with its probability
+type EndgameResult struct {
+	// Coup is the best coup of the Ally race
+	Coup model.Coup
+	// Win, Lose and Draw are the probabilities that the game ends within Plies coups by a win, a loss or both races
+	// being wiped out by the same battle, the rest is the probability that it lasts longer
+	Win  float64
+	Lose float64
+	Draw float64
+	// Exact is true when no line of play lasts longer than Plies coups, searching further would change nothing
+	Exact bool
+	// Plies is the number of coups of both sides searched, the coup of the Ally race counting as the first one. When
+	// the win is forced, it is the length of the fastest forced win
+	Plies uint8
+	// Nodes is the number of positions searched
+	Nodes uint64
+}
+
+// Solved tells if Coup is known to be the best coup: the outcome is exact or the win is forced
+func (r EndgameResult) Solved() bool {
+	return r.Exact || r.ForcedWin()
+}
+
+// ForcedWin tells if the Ally race wins for sure within Plies coups
+func (r EndgameResult) ForcedWin() bool {
+	return r.Win >= certain
+}
+
+// endgameKey identifies a position of the solver, the race to play is part of the hash
+type endgameKey struct {
+	hash  uint64
+	plies uint8
+}
+
+type endgameValue struct {
+	win, lose, draw float64
+	// open is true if a line of play reached the horizon, a further horizon could then change the value
+	open bool
+}
+
+// better tells if v is better than o for race, the Ally race maximizes its chances of winning minus its chances of
+// losing and the Enemy race minimizes them
+func (v endgameValue) better(o endgameValue, race model.Race) bool {
+	d, od := v.win-v.lose, o.win-o.lose
+	if race == model.Enemy {
+		d, od = -d, -od
+	}
+	return d > od
+}
+
+// best tells if nothing can be better than v for race
+func (v endgameValue) best(race model.Race) bool {
+	if race == model.Enemy {
+		return v.lose >= certain
+	}
+	return v.win >= certain
+}
+
+// finalValue is a value which no further horizon changes, found with plies coups left
+type finalValue struct {
+	value endgameValue
+	plies uint8
+}
+
+type endgameSolver struct {
+	ctx   context.Context
+	memo  map[endgameKey]endgameValue
+	final map[uint64]finalValue
+	nodes uint64
+	// hashBuffer is reused by State.Hash
+	hashBuffer []uint32
+}
+
+func hasGroups(s *model.State, race model.Race) bool {
+	for _, cell := range s.Grid {
+		if cell.Race == race && !cell.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// SolveEndgame searches every coup of both sides and every outcome of the battles, up to limits.Plies coups. The
+// horizon grows one coup at a time so that the first forced win found is the fastest one, it stops once the best
+// coup is known or when ctx is done, the result of the last complete horizon is then returned. ok is false if the
+// state exceeds the limits or if not even one coup could be searched
+func SolveEndgame(ctx context.Context, s *model.State, limits EndgameLimits) (res EndgameResult, ok bool) {
+	if !limits.Contains(s) {
+		return res, false
+	}
+
+	solver := &endgameSolver{
+		ctx:        ctx,
+		memo:       map[endgameKey]endgameValue{},
+		final:      map[uint64]finalValue{},
+		hashBuffer: make([]uint32, 0, 32),
+	}
+	for plies := uint8(1); plies <= limits.Plies; plies++ {
+		coup, v, complete := solver.root(s, plies)
+		if !complete {
+			break
+		}
+		res, ok = EndgameResult{Coup: coup, Win: v.win, Lose: v.lose, Draw: v.draw, Exact: !v.open, Plies: plies}, true
+		if res.Solved() {
+			break
+		}
+	}
+	res.Nodes = solver.nodes
+	return res, ok
+}
+
+// root returns the best coup of the Ally race, complete is false if the search was interrupted. Among the coups as
+// good as each other, the one with the fewest moves moving the most units is preferred
+func (e *endgameSolver) root(s *model.State, plies uint8) (best model.Coup, value endgameValue, complete bool) {
+	first, open := true, false
+	complete = forEachEndgameCoup(s, model.Ally, func(coup model.Coup) bool {
+		v, complete := e.coup(s, model.Ally, coup, plies)
+		if !complete {
+			return false
+		}
+		open = open || v.open
+		if first || v.better(value, model.Ally) || (!value.better(v, model.Ally) && simpler(coup, best)) {
+			first = false
+			best, value = append(model.Coup{}, coup...), v
+		}
+		return true
+	})
+	value.open = open
+	return best, value, complete && !first
+}
+
+func simpler(c1, c2 model.Coup) bool {
+	if len(c1) != len(c2) {
+		return len(c1) < len(c2)
+	}
+	var n1, n2 int
+	for i := range c1 {
+		n1 += int(c1[i].N)
+		n2 += int(c2[i].N)
+	}
+	return n1 > n2
+}
+
+// coup returns the value of playing coup, averaged over the outcomes of its battles
+func (e *endgameSolver) coup(s *model.State, race model.Race, coup model.Coup, plies uint8) (value endgameValue, complete bool) {
+	for _, outcome := range endgameOutcomes(s, race, coup) {
+		v, complete := e.value(outcome.State, race.Opponent(), plies-1)
+		if !complete {
+			return value, false
+		}
+		value.win += outcome.P * v.win
+		value.lose += outcome.P * v.lose
+		value.draw += outcome.P * v.draw
+		value.open = value.open || v.open
+	}
+	return value, true
+}
+
+// value returns the value of a state where race plays, with plies coups left
+func (e *endgameSolver) value(s *model.State, race model.Race, plies uint8) (value endgameValue, complete bool) {
+	select {
+	case <-e.ctx.Done():
+		return value, false
+	default:
+	}
+	e.nodes++
+
+	allies, enemies := hasGroups(s, model.Ally), hasGroups(s, model.Enemy)
+	switch {
+	case !allies && !enemies:
+		// Both races were wiped out by the same battle
+		return endgameValue{draw: 1}, true
+	case !allies:
+		return endgameValue{lose: 1}, true
+	case !enemies:
+		return endgameValue{win: 1}, true
+	case plies == 0:
+		return endgameValue{open: true}, true
+	}
+
+	hash := s.Hash(race, e.hashBuffer)
+	if f, ok := e.final[hash]; ok && f.plies <= plies {
+		return f.value, true
+	}
+	key := endgameKey{hash: hash, plies: plies}
+	if v, ok := e.memo[key]; ok {
+		return v, true
+	}
+
+	first, open, interrupted := true, false, false
+	forEachEndgameCoup(s, race, func(coup model.Coup) bool {
+		v, complete := e.coup(s, race, coup, plies)
+		if !complete {
+			interrupted = true
+			return false
+		}
+		open = open || v.open
+		if first || v.better(value, race) {
+			first = false
+			value = v
+		}
+		if value.best(race) {
+			// The other coups can't do better, however long the game lasts
+			open = false
+			return false
+		}
+		return true
+	})
+	if interrupted {
+		return value, false
+	}
+	if first {
+		// The groups can't move, nothing is known
+		return endgameValue{open: true}, true
+	}
+	value.open = open
+
+	e.memo[key] = value
+	if !open {
+		e.final[hash] = finalValue{value: value, plies: plies}
+	}
+	return value, true
+}
+
+// neighbours lists the offsets of the 8 cells around a cell
+var neighbours = []transformation{
+	{0, -1}, {0, 1}, {1, 0}, {-1, 0}, {1, -1}, {1, 1}, {-1, -1}, {-1, 1},
+}
+
+// forEachEndgameCoup calls f with every coup of race allowed by model.ValidateCoup, until f returns false: each group
+// sends any number of its units to each of its neighbouring cells and keeps the rest. The moves of a group are
+// visited from the largest to the smallest, so that the whole group moving comes first. The coup given to f is
+// reused, it must be copied to be kept. It returns false if f stopped the visit
+func forEachEndgameCoup(s *model.State, race model.Race, f func(coup model.Coup) bool) bool {
+	var groups []model.Coordinates
+	for c, cell := range s.Grid {
+		if cell.Race == race && !cell.IsEmpty() {
+			groups = append(groups, c)
+		}
+	}
+	if len(groups) == 0 {
+		return true
+	}
+	// The map is iterated in a random order, sort the groups for the coups to be deterministic
+	sort.Slice(groups, func(i, j int) bool { return model.LessCoordinates(groups[i], groups[j]) })
+
+	targets := make([][]model.Coordinates, len(groups))
+	for i, c := range groups {
+		for _, t := range neighbours {
+			if target, ok := transform(s.Width, s.Height, c, t); ok {
+				targets[i] = append(targets[i], target)
+			}
+		}
+	}
+
+	var coup model.Coup
+	// visit sends from 0 to left units of the group g to its target t, then visits the next targets and groups
+	var visit func(g, t int, left uint8) bool
+	visit = func(g, t int, left uint8) bool {
+		if t == len(targets[g]) {
+			if g+1 < len(groups) {
+				return visit(g+1, 0, s.Grid[groups[g+1]].Count)
+			}
+			// The empty coup and the ones using a cell as both a start and an end are dropped
+			if model.ValidateCoup(s, race, coup) != nil {
+				return true
+			}
+			return f(coup)
+		}
+
+		for n := int(left); n >= 0; n-- {
+			if n > 0 {
+				coup = append(coup, model.Move{Start: groups[g], N: uint8(n), End: targets[g][t]})
+			}
+			ok := visit(g, t+1, left-uint8(n))
+			if n > 0 {
+				coup = coup[:len(coup)-1]
+			}
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return visit(0, 0, s.Grid[groups[0]].Count)
+}
+
+// endgameOutcomes returns every state that can follow a coup of race with its probability, following the rules of
+// the server: the units arriving on a cell fight its occupants at once, when the battle is random the winners
+// survive one by one with the probability to win, and the humans are converted one by one with that same probability
+func endgameOutcomes(s *model.State, race model.Race, coup model.Coup) []model.PotentialState {
+	next := s.Copy(true)
+	arrivals := map[model.Coordinates]uint8{}
+	var targets []model.Coordinates
+	for _, move := range coup {
+		next.DecreaseCell(move.Start, race, move.N)
+		if _, ok := arrivals[move.End]; !ok {
+			targets = append(targets, move.End)
+		}
+		arrivals[move.End] = addUnits(arrivals[move.End], move.N)
+	}
+
+	outcomes := []model.PotentialState{{State: next, P: 1}}
+	for _, target := range targets {
+		count := arrivals[target]
+		cell := next.Grid[target]
+		if cell.IsEmpty() || cell.Race == race {
+			for _, o := range outcomes {
+				o.SetCell(target, race, addUnits(cell.Count, count))
+			}
+			continue
+		}
+
+		neutral := cell.Race == model.Neutral
+		p := model.WinProbability(count, cell.Count, neutral)
+		winners := count
+		if neutral {
+			winners = addUnits(winners, cell.Count)
+		}
+
+		var results []battleResult
+		if p == 1 {
+			results = []battleResult{{race: race, count: winners, p: 1}}
+		} else {
+			results = append(survivors(race, winners, p, p), survivors(cell.Race, cell.Count, 1-p, 1-p)...)
+		}
+
+		var split []model.PotentialState
+		for _, o := range outcomes {
+			for _, r := range results {
+				state := o.State.Copy(false)
+				if r.count == 0 {
+					state.EmptyCell(target)
+				} else {
+					state.SetCell(target, r.race, r.count)
+				}
+				split = append(split, model.PotentialState{State: state, P: o.P * r.p})
+			}
+		}
+		outcomes = split
+	}
+	return outcomes
+}
+
+// addUnits adds two counts of units, saturating at the capacity of a cell. Contains keeps the states solved below it
+func addUnits(a, b uint8) uint8 {
+	if int(a)+int(b) > math.MaxUint8 {
+		return math.MaxUint8
+	}
+	return a + b
+}
+
+// battleResult is a possible end of a battle: count units of race are left on the cell
+type battleResult struct {
+	race  model.Race
+	count uint8
+	p     float64
+}
+
+// survivors returns the results of a battle won with probability win by n units of race, each surviving with
+// probability p
+func survivors(race model.Race, n uint8, win, p float64) []battleResult {
+	results := make([]battleResult, 0, int(n)+1)
+	for k := 0; k <= int(n); k++ {
+		binomial := math.Exp(lgamma(int(n)+1)-lgamma(k+1)-lgamma(int(n)-k+1)) * math.Pow(p, float64(k)) * math.Pow(1-p, float64(int(n)-k))
+		if binomial > 0 {
+			results = append(results, battleResult{race: race, count: uint8(k), p: win * binomial})
+		}
+	}
+	return results
+}
+
+func lgamma(n int) float64 {
+	v, _ := math.Lgamma(float64(n))
+	return v
+}
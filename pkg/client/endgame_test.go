@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEndgameLimits enables the solver, which is disabled by default
+var testEndgameLimits = EndgameLimits{Groups: 3, Units: 24, Plies: 10}
+
+func solve(t *testing.T, s *model.State) EndgameResult {
+	res, ok := SolveEndgame(context.Background(), s, testEndgameLimits)
+	require.True(t, ok)
+	return res
+}
+
+func TestSolveEndgame(t *testing.T) {
+	t.Run("immediate win", func(t *testing.T) {
+		s := model.NewState(3, 3)
+		s.SetCell(model.Coordinates{X: 0, Y: 0}, model.Ally, 10)
+		s.SetCell(model.Coordinates{X: 1, Y: 1}, model.Enemy, 4)
+		s.SetCell(model.Coordinates{X: 2, Y: 2}, model.Neutral, 3)
+
+		res := solve(t, s)
+		assert.True(t, res.ForcedWin())
+		assert.Equal(t, uint8(1), res.Plies)
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{}, N: 10, End: model.Coordinates{X: 1, Y: 1}}}, res.Coup)
+	})
+
+	t.Run("fastest forced win", func(t *testing.T) {
+		// Walking to the enemies gives them a chance to win their attack, keeping units back doesn't
+		s := model.NewState(1, 3)
+		s.SetCell(model.Coordinates{X: 0}, model.Ally, 6)
+		s.SetCell(model.Coordinates{X: 2}, model.Enemy, 2)
+
+		res := solve(t, s)
+		assert.True(t, res.ForcedWin())
+		assert.Equal(t, uint8(5), res.Plies)
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 0}, N: 2, End: model.Coordinates{X: 1}}}, res.Coup)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		// Only both groups taken at once win for sure
+		s := model.NewState(1, 3)
+		s.SetCell(model.Coordinates{X: 0}, model.Enemy, 2)
+		s.SetCell(model.Coordinates{X: 1}, model.Ally, 6)
+		s.SetCell(model.Coordinates{X: 2}, model.Enemy, 2)
+
+		res := solve(t, s)
+		assert.True(t, res.ForcedWin())
+		assert.Equal(t, uint8(1), res.Plies)
+		assert.ElementsMatch(t, model.Coup{
+			{Start: model.Coordinates{X: 1}, N: 3, End: model.Coordinates{X: 0}},
+			{Start: model.Coordinates{X: 1}, N: 3, End: model.Coordinates{X: 2}},
+		}, res.Coup)
+	})
+
+	t.Run("coin flip", func(t *testing.T) {
+		// The allies have to attack as many enemies, the winners survive one by one with a probability of 1/2
+		s := model.NewState(1, 2)
+		s.SetCell(model.Coordinates{X: 0}, model.Ally, 4)
+		s.SetCell(model.Coordinates{X: 1}, model.Enemy, 4)
+
+		res := solve(t, s)
+		assert.True(t, res.Exact)
+		assert.True(t, res.Solved())
+		assert.False(t, res.ForcedWin())
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 0}, N: 4, End: model.Coordinates{X: 1}}}, res.Coup)
+		assert.InDelta(t, 15./32, res.Win, 1e-9)
+		assert.InDelta(t, 15./32, res.Lose, 1e-9)
+		assert.InDelta(t, 1./16, res.Draw, 1e-9)
+	})
+
+	t.Run("humans first", func(t *testing.T) {
+		// Attacking right away wins a third of the time, converting the humans first is way better
+		s := model.NewState(1, 3)
+		s.SetCell(model.Coordinates{X: 0}, model.Neutral, 2)
+		s.SetCell(model.Coordinates{X: 1}, model.Ally, 2)
+		s.SetCell(model.Coordinates{X: 2}, model.Enemy, 3)
+
+		res := solve(t, s)
+		assert.Greater(t, res.Win, 0.8)
+		assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 1}, N: 2, End: model.Coordinates{X: 0}}}, res.Coup)
+	})
+}
+
+func TestEndgameCoups(t *testing.T) {
+	coups := func(s *model.State) []model.Coup {
+		var res []model.Coup
+		forEachEndgameCoup(s, model.Ally, func(coup model.Coup) bool {
+			require.NoError(t, model.ValidateCoup(s, model.Ally, coup))
+			res = append(res, append(model.Coup{}, coup...))
+			return true
+		})
+		return res
+	}
+
+	// 2 units sent to both sides or kept in every way but all kept
+	s := model.NewState(1, 3)
+	s.SetCell(model.Coordinates{X: 1}, model.Ally, 2)
+	assert.Len(t, coups(s), 5)
+	assert.Equal(t, model.Coup{{Start: model.Coordinates{X: 1}, N: 2, End: model.Coordinates{X: 2}}}, coups(s)[0])
+
+	// A group which moves can't receive units
+	s = model.NewState(1, 3)
+	s.SetCell(model.Coordinates{X: 0}, model.Ally, 1)
+	s.SetCell(model.Coordinates{X: 1}, model.Ally, 1)
+	assert.ElementsMatch(t, []model.Coup{
+		{{Start: model.Coordinates{X: 0}, N: 1, End: model.Coordinates{X: 1}}},
+		{{Start: model.Coordinates{X: 1}, N: 1, End: model.Coordinates{X: 0}}},
+		{{Start: model.Coordinates{X: 1}, N: 1, End: model.Coordinates{X: 2}}},
+	}, coups(s))
+
+	// Every coup of a bigger group, stopped on demand
+	s = model.NewState(3, 3)
+	s.SetCell(model.Coordinates{X: 1, Y: 1}, model.Ally, 3)
+	n := 0
+	assert.False(t, forEachEndgameCoup(s, model.Ally, func(coup model.Coup) bool {
+		n++
+		return n < 10
+	}))
+	assert.Equal(t, 10, n)
+	// 3 units spread over 9 cells, all kept excluded
+	assert.Len(t, coups(s), 164)
+}
+
+func TestEndgameOutcomes(t *testing.T) {
+	s := model.NewState(1, 2)
+	s.SetCell(model.Coordinates{X: 0}, model.Ally, 4)
+	s.SetCell(model.Coordinates{X: 1}, model.Enemy, 4)
+
+	outcomes := endgameOutcomes(s, model.Ally, model.Coup{{Start: model.Coordinates{X: 0}, N: 4, End: model.Coordinates{X: 1}}})
+	// From 0 to 4 survivors of either race
+	require.Len(t, outcomes, 10)
+	total, wipedOut := 0., 0.
+	for _, o := range outcomes {
+		total += o.P
+		if len(o.Grid) == 0 {
+			wipedOut += o.P
+		}
+	}
+	assert.InDelta(t, 1, total, 1e-9)
+	// The battle is won or lost with no survivor
+	assert.InDelta(t, 1./16, wipedOut, 1e-9)
+
+	// Humans are converted one by one
+	s = model.NewState(1, 2)
+	s.SetCell(model.Coordinates{X: 0}, model.Ally, 2)
+	s.SetCell(model.Coordinates{X: 1}, model.Neutral, 4)
+	outcomes = endgameOutcomes(s, model.Ally, model.Coup{{Start: model.Coordinates{X: 0}, N: 2, End: model.Coordinates{X: 1}}})
+	// Won with 0 to 6 survivors, lost with 0 to 4 humans left
+	require.Len(t, outcomes, 12)
+	var expected float64
+	for _, o := range outcomes {
+		if cell := o.Grid[model.Coordinates{X: 1}]; cell.Race == model.Ally {
+			expected += o.P * float64(cell.Count)
+		}
+	}
+	// Won a quarter of the time, each of the 6 units surviving a quarter of the time
+	assert.InDelta(t, 6./16, expected, 1e-9)
+
+	// A certain win keeps every unit
+	s = model.NewState(1, 2)
+	s.SetCell(model.Coordinates{X: 0}, model.Ally, 6)
+	s.SetCell(model.Coordinates{X: 1}, model.Enemy, 4)
+	outcomes = endgameOutcomes(s, model.Ally, model.Coup{{Start: model.Coordinates{X: 0}, N: 6, End: model.Coordinates{X: 1}}})
+	require.Len(t, outcomes, 1)
+	assert.Equal(t, model.Cell{Race: model.Ally, Count: 6}, outcomes[0].Grid[model.Coordinates{X: 1}])
+}
+
+func TestSolveEndgameLimits(t *testing.T) {
+	_, ok := SolveEndgame(context.Background(), model.GenerateComplicatedState(), testEndgameLimits)
+	assert.False(t, ok)
+
+	s := model.NewState(1, 4)
+	s.SetCell(model.Coordinates{X: 0}, model.Ally, 10)
+	s.SetCell(model.Coordinates{X: 3}, model.Enemy, 4)
+	assert.True(t, testEndgameLimits.Contains(s))
+	assert.False(t, NewDefaultEndgameLimits().Contains(s))
+	// The units must fit in a cell
+	big := model.NewState(1, 4)
+	big.SetCell(model.Coordinates{X: 0}, model.Ally, 200)
+	big.SetCell(model.Coordinates{X: 3}, model.Enemy, 100)
+	assert.False(t, EndgameLimits{Groups: 3, Units: 1000, Plies: 10}.Contains(big))
+	assert.Equal(t, uint8(255), addUnits(200, 100))
+	assert.False(t, EndgameLimits{Groups: 1, Units: 100, Plies: 10}.Contains(s))
+	assert.False(t, EndgameLimits{Groups: 2, Units: 10, Plies: 10}.Contains(s))
+	assert.False(t, EndgameLimits{}.Contains(s))
+
+	// Too short to find the win
+	res, ok := SolveEndgame(context.Background(), s, EndgameLimits{Groups: 2, Units: 14, Plies: 2})
+	require.True(t, ok)
+	assert.False(t, res.Solved())
+	assert.Equal(t, uint8(2), res.Plies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok = SolveEndgame(ctx, s, testEndgameLimits)
+	assert.False(t, ok)
+}
+
+func TestMinMaxIAEndgame(t *testing.T) {
+	s := model.NewState(3, 3)
+	s.SetCell(model.Coordinates{X: 0, Y: 0}, model.Ally, 10)
+	s.SetCell(model.Coordinates{X: 1, Y: 1}, model.Enemy, 4)
+	want := model.Coup{{Start: model.Coordinates{}, N: 10, End: model.Coordinates{X: 1, Y: 1}}}
+
+	// Way longer than the test if it searched
+	ia, err := NewIA("minmax:timeout=10s,endgame_groups=3")
+	require.NoError(t, err)
+	start := time.Now()
+	assert.Equal(t, want, ia.Play(s))
+	assert.WithinDuration(t, start, time.Now(), time.Second)
+
+	_, err = NewIA("minmax:endgame_units=many")
+	assert.Error(t, err)
+
+	// Disabled by default, the search uses its time budget
+	ia, err = NewIA("minmax:timeout=100ms")
+	require.NoError(t, err)
+	start = time.Now()
+	ia.Play(s)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
+
+func TestEndgameInReach(t *testing.T) {
+	s := model.NewState(1, 10)
+	s.SetCell(model.Coordinates{X: 0}, model.Ally, 10)
+	s.SetCell(model.Coordinates{X: 5}, model.Enemy, 4)
+	assert.True(t, EndgameLimits{Plies: 9}.InReach(s))
+	assert.False(t, EndgameLimits{Plies: 8}.InReach(s))
+
+	s.SetCell(model.Coordinates{X: 9}, model.Enemy, 2)
+	assert.False(t, EndgameLimits{Plies: 9}.InReach(s))
+}
+
+func TestMinMaxIAUnsolvedEndgame(t *testing.T) {
+	// Small but with the groups too far apart to be solved within the plies
+	s := model.NewState(10, 10)
+	s.SetCell(model.Coordinates{X: 0, Y: 0}, model.Ally, 16)
+	s.SetCell(model.Coordinates{X: 9, Y: 9}, model.Enemy, 8)
+	require.True(t, testEndgameLimits.Contains(s))
+
+	for _, spec := range []string{"minmax:timeout=200ms", "minmax:timeout=200ms,endgame_groups=3"} {
+		ia, err := NewIA(spec)
+		require.NoError(t, err)
+		m := ia.(*MinMaxIA)
+
+		// The search keeps its whole time budget, and so its depth
+		h, pm := m.search()
+		_, ok, timeout := m.solveEndgame(context.Background(), &h, s, pm)
+		assert.False(t, ok, spec)
+		assert.Equal(t, 200*time.Millisecond, timeout, spec)
+	}
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"math"
+	"sort"
+
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// Names of the terms of an Explanation
+const (
+	TermCounts         = "counts"
+	TermBattles        = "battles"
+	TermNeutralBattles = "neutral_battles"
+	TermGroups         = "groups"
+	TermTerritory      = "territory"
+	TermEvaluator      = "evaluator"
+	TermCumScore       = "cum_score"
+)
+
+// Outcomes of an Explanation
+const (
+	OutcomeWin  = "win"
+	OutcomeLose = "lose"
+)
+
+// Term is one of the terms summed by the heuristic
+type Term struct {
+	Name string
+	// Ally and Enemy are the raw values of both races, Value is their difference
+	Ally  float64
+	Enemy float64
+	Value float64
+	// Coef is the coefficient of the term, Contribution is Value times Coef
+	Coef         float64
+	Contribution float64
+}
+
+// BattlePair is a battle considered by the heuristic between a monster group and another group
+type BattlePair struct {
+	Target model.Coordinates
+	Cell   model.Cell
+	// Neutral is true for a battle against humans, Gain is then the probable gain of population of the attacker
+	// divided by the distance. Against monsters, Gain is the score of the attack for the race of the group and
+	// Loss the score of the counter attack for the opponent, both divided by the distance
+	Neutral  bool
+	Distance float64
+	Gain     float64
+	Loss     float64 `json:",omitempty"`
+}
+
+// CellExplanation lists the battles considered from a monster group
+type CellExplanation struct {
+	Coords  model.Coordinates
+	Cell    model.Cell
+	Battles []BattlePair
+}
+
+// Explanation breaks down the score given by a heuristic to a state
+type Explanation struct {
+	// Score is the score of the state, as used by the search
+	Score float64
+	// Outcome is OutcomeWin or OutcomeLose when a race has no unit left, the terms are then replaced by the win or
+	// lose score
+	Outcome string `json:",omitempty"`
+	// Terms are every term of the heuristic, the ones with a coefficient of 0 or replaced by an evaluator contribute 0
+	Terms []Term
+	// Cells are the monster groups, sorted by coordinates
+	Cells []CellExplanation
+}
+
+// Term returns the term with the given name
+func (e *Explanation) Term(name string) (Term, bool) {
+	for _, t := range e.Terms {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Term{}, false
+}
+
+func newTerm(t heuristicTerm) Term {
+	value := t.scores.ally - t.scores.enemy
+	return Term{Name: t.name, Ally: t.scores.ally, Enemy: t.scores.enemy, Value: value, Coef: t.coef, Contribution: t.contribution()}
+}
+
+// Explain computes the score of a state like the search does and details every term of it. Unlike the search, the
+// raw values of the terms are computed even when their coefficient is 0
+func (h *Heuristic) Explain(s *model.State) Explanation {
+	groups := map[model.Coordinates]*CellExplanation{}
+	for c, cell := range s.Grid {
+		if cell.Race != model.Neutral {
+			groups[c] = &CellExplanation{Coords: c, Cell: cell}
+		}
+	}
+
+	terms := h.stateTerms(s, true, func(c model.Coordinates, pair BattlePair) {
+		groups[c].Battles = append(groups[c].Battles, pair)
+	})
+	counts := terms[0].scores
+
+	e := Explanation{}
+	for _, ce := range groups {
+		sort.Slice(ce.Battles, func(i, j int) bool { return model.LessCoordinates(ce.Battles[i].Target, ce.Battles[j].Target) })
+		e.Cells = append(e.Cells, *ce)
+	}
+	sort.Slice(e.Cells, func(i, j int) bool { return model.LessCoordinates(e.Cells[i].Coords, e.Cells[j].Coords) })
+
+	for _, t := range terms {
+		e.Terms = append(e.Terms, newTerm(t))
+	}
+
+	cumScore := s.CumulativeScore * h.CumScore
+
+	switch {
+	case counts.ally == 0:
+		e.Outcome = OutcomeLose
+		e.Score = -(h.WinScore * h.LoseOverWinRatio) + cumScore
+	case counts.enemy == 0:
+		e.Outcome = OutcomeWin
+		e.Score = h.WinScore - cumScore
+	}
+
+	if e.Outcome != "" {
+		// The terms don't count in a won or lost state
+		for i := range e.Terms {
+			e.Terms[i].Contribution = 0
+		}
+	} else if h.evaluator != nil {
+		value := h.evaluator.Evaluate(s)
+		e.Terms = append(e.Terms, Term{Name: TermEvaluator, Ally: value, Value: value, Coef: 1, Contribution: value})
+	}
+
+	cumTerm := Term{Name: TermCumScore, Ally: s.CumulativeScore, Value: s.CumulativeScore, Coef: h.CumScore, Contribution: cumScore}
+	if e.Outcome == OutcomeWin {
+		// The earliest win is the best one
+		cumTerm.Contribution = -cumScore
+	}
+	e.Terms = append(e.Terms, cumTerm)
+
+	if e.Outcome == "" {
+		for _, t := range e.Terms {
+			e.Score += t.Contribution
+		}
+	}
+
+	return e
+}
+
+// Largest returns the terms sorted by decreasing absolute contribution
+func (e *Explanation) Largest() []Term {
+	terms := append([]Term{}, e.Terms...)
+	sort.SliceStable(terms, func(i, j int) bool {
+		return math.Abs(terms[i].Contribution) > math.Abs(terms[j].Contribution)
+	})
+	return terms
+}
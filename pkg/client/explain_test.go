@@ -0,0 +1,122 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type constantEvaluator float64
+
+func (c constantEvaluator) Evaluate(s *model.State) float64 { return float64(c) }
+func (c constantEvaluator) Name() string                    { return "constant" }
+
+func TestExplainMatchesScore(t *testing.T) {
+	params := NewDefaultHeuristicParameters()
+	params.Groups = -2
+	params.Territory = 0.5
+	withoutBattles := NewDefaultHeuristicParameters()
+	withoutBattles.Battles, withoutBattles.NeutralBattles = 0, 0
+
+	heuristics := map[string]Heuristic{
+		"default":   NewHeuristic(NewDefaultHeuristicParameters()),
+		"all terms": NewHeuristic(params),
+		"no battle": NewHeuristic(withoutBattles),
+		"evaluator": NewHeuristicWithEvaluator(params, constantEvaluator(3)),
+	}
+
+	won := model.GenerateSimpleState()
+	for c, cell := range won.Grid {
+		if cell.Race == model.Enemy {
+			won.EmptyCell(c)
+		}
+	}
+	lost := model.GenerateSimpleState()
+	for c, cell := range lost.Grid {
+		if cell.Race == model.Ally {
+			lost.EmptyCell(c)
+		}
+	}
+	states := map[string]*model.State{
+		"simple":      model.GenerateSimpleState(),
+		"complicated": model.GenerateComplicatedState(),
+		"won":         won,
+		"lost":        lost,
+	}
+
+	for hn, h := range heuristics {
+		for sn, s := range states {
+			s.CumulativeScore = 100
+			e := h.Explain(s)
+			assert.InDelta(t, h.scoreState(s), e.Score, 1e-6, "%s on %s", hn, sn)
+		}
+	}
+
+	h := heuristics["default"]
+	assert.Equal(t, OutcomeWin, h.Explain(won).Outcome)
+	assert.Equal(t, OutcomeLose, h.Explain(lost).Outcome)
+}
+
+func TestExplain(t *testing.T) {
+	s := model.NewState(5, 5)
+	s.SetCell(model.Coordinates{X: 0, Y: 0}, model.Ally, 10)
+	s.SetCell(model.Coordinates{X: 4, Y: 4}, model.Enemy, 6)
+	s.SetCell(model.Coordinates{X: 2, Y: 0}, model.Neutral, 4)
+
+	params := NewDefaultHeuristicParameters()
+	params.Battles = 0
+	h := NewHeuristic(params)
+	e := h.Explain(s)
+
+	assert.Equal(t, "", e.Outcome)
+	assert.Equal(t, []string{TermCounts, TermBattles, TermNeutralBattles, TermGroups, TermTerritory, TermCumScore}, termNames(e.Terms))
+
+	counts, ok := e.Term(TermCounts)
+	require.True(t, ok)
+	assert.Equal(t, Term{Name: TermCounts, Ally: 10, Enemy: 6, Value: 4, Coef: 1, Contribution: 4}, counts)
+
+	// Computed although its coefficient is 0
+	battles, _ := e.Term(TermBattles)
+	assert.NotZero(t, battles.Value)
+	assert.Zero(t, battles.Contribution)
+
+	territory, _ := e.Term(TermTerritory)
+	assert.Equal(t, 4., territory.Ally)
+	assert.Zero(t, territory.Contribution)
+
+	assert.Equal(t, TermCounts, e.Largest()[0].Name)
+
+	// The monster groups sorted by coordinates, each with its battles
+	require.Len(t, e.Cells, 2)
+	ally := e.Cells[0]
+	assert.Equal(t, model.Coordinates{X: 0, Y: 0}, ally.Coords)
+	require.Len(t, ally.Battles, 2)
+	assert.Equal(t, model.Coordinates{X: 2, Y: 0}, ally.Battles[0].Target)
+	assert.True(t, ally.Battles[0].Neutral)
+	assert.Equal(t, 2., ally.Battles[0].Distance)
+	assert.Equal(t, scoreNeutralBattle(ally.Coords, ally.Battles[0].Target, s.Grid[ally.Coords], s.Grid[ally.Battles[0].Target]), ally.Battles[0].Gain)
+	assert.Equal(t, model.Coordinates{X: 4, Y: 4}, ally.Battles[1].Target)
+	assert.False(t, ally.Battles[1].Neutral)
+	assert.Equal(t, model.Coordinates{X: 4, Y: 4}, e.Cells[1].Coords)
+
+	// With an evaluator, the terms are still explained but only the evaluator counts
+	h = NewHeuristicWithEvaluator(params, constantEvaluator(3))
+	e = h.Explain(s)
+	evaluator, ok := e.Term(TermEvaluator)
+	require.True(t, ok)
+	assert.Equal(t, 3., evaluator.Contribution)
+	counts, _ = e.Term(TermCounts)
+	assert.Equal(t, 4., counts.Value)
+	assert.Zero(t, counts.Contribution)
+	assert.InDelta(t, 3., e.Score, 1e-9)
+}
+
+func termNames(terms []Term) []string {
+	names := make([]string, len(terms))
+	for i, t := range terms {
+		names[i] = t.Name
+	}
+	return names
+}
@@ -2,7 +2,10 @@ package client
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
 )
 
 // Game implements the Client interface using a TCP server
@@ -10,11 +13,38 @@ type Game struct {
 	state      *model.State
 	playerName string
 	ia         IA
+	watchdog   *watchdog
+	turn       int // number of coups asked to the IA in the current game
+	log        *logging.Logger
+	metrics    *PlayerMetrics // nil if disabled
 }
 
 // NewGame creates a new TCP client
 func NewGame(name string, ia IA) *Game {
-	return &Game{playerName: name, ia: ia}
+	return &Game{playerName: name, ia: ia, watchdog: newWatchdog(), log: logging.Default()}
+}
+
+// SetLogger sets the logger of the game, the turn field is added to the logs of the IA
+func (g *Game) SetLogger(l *logging.Logger) {
+	g.log = l
+}
+
+// SetMetrics enables the metrics of the game, they are also given to the IA if it is Instrumented
+func (g *Game) SetMetrics(m *PlayerMetrics) {
+	g.metrics = m
+	if i, ok := g.ia.(Instrumented); ok {
+		i.SetMetrics(m)
+	}
+}
+
+// nextTurn starts a turn and gives its logger to the IA
+func (g *Game) nextTurn() *logging.Logger {
+	g.turn++
+	log := g.log.With("turn", g.turn)
+	if l, ok := g.ia.(Logged); ok {
+		l.SetLogger(log)
+	}
+	return log
 }
 
 // Nme defines the player name
@@ -23,12 +53,26 @@ func (g *Game) Nme() string {
 }
 
 func (g *Game) Mov() []model.Move {
-	return g.ia.Play(g.state.Copy(false))
+	start := time.Now()
+	g.nextTurn()
+	coup := g.ia.Play(g.state.Copy(false))
+	g.metrics.observeMove(g.state, coup, g.turn, time.Since(start), 0)
+	return coup
+}
+
+// MovBefore is the same as Mov but always returns before limit, received is the time at which UPD was received
+func (g *Game) MovBefore(received time.Time, limit time.Time) []model.Move {
+	log := g.nextTurn()
+	coup := g.watchdog.play(g.ia, g.state.Copy(false), received, limit, log)
+	g.metrics.observeMove(g.state, coup, g.turn, time.Since(received), limit.Sub(received))
+	return coup
 }
 
 // Set initialize an empty grid in the state
 func (g *Game) Set(n uint8, m uint8) {
 	g.state = model.NewState(n, m)
+	g.turn = 0
+	g.metrics.gameStarted()
 }
 
 func (g *Game) Hum(coords []model.Coordinates) {
@@ -58,6 +102,7 @@ func (g *Game) Upd(changes []model.Changes) {
 			panic(fmt.Sprintf("impossible change, maximum one race per cell: %+v", change))
 		}
 	}
+	g.metrics.observeState(g.state)
 }
 
 // Map is the same as Upd but is called only once at the beginning
@@ -65,9 +110,12 @@ func (g *Game) Map(changes []model.Changes) {
 	g.Upd(changes)
 }
 
-// End delete the state of the game
+// End deletes the state of the game and resets the IA for the next one
 func (g *Game) End() error {
-	g = &Game{}
+	g.state = nil
+	if r, ok := g.ia.(Resetter); ok {
+		r.Reset()
+	}
 	return nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
 )
 
 // HeuristicParameters defines the parameters used to compute the heuristic
@@ -38,6 +39,9 @@ type HeuristicParameters struct {
 	// Groups is used to penalize/reward the fact of having a lot of scattered units
 	// We want it to be negative since we want to penalize the fact of having a lot of scattered units
 	Groups float64
+
+	// Territory is a coefficient for the humans each side reaches first with enough units to convert them
+	Territory float64
 }
 
 const (
@@ -50,6 +54,7 @@ const (
 	DefaultWinThreshold     = 1
 	DefaultMaxGroups        = 2
 	DefaultGroups           = 0
+	DefaultTerritory        = 0
 )
 
 func (hp *HeuristicParameters) String() string {
@@ -58,10 +63,15 @@ func (hp *HeuristicParameters) String() string {
 
 // ShortString give a smaller string representation for heuristic parameters. Useful to name an ia in short way.
 func (hp *HeuristicParameters) ShortString() string {
-	return fmt.Sprintf(
+	s := fmt.Sprintf(
 		"c%3.2f_b%3.2f_nb%3.2f_cs%4.3f_ws%3.2e_lowr%3.2f_wt%3.2f_mg%d_g%1.0f",
 		hp.Counts, hp.Battles, hp.NeutralBattles, hp.CumScore, hp.WinScore, hp.LoseOverWinRatio, hp.WinThreshold, hp.MaxGroups, hp.Groups,
 	)
+	// Only shown when used to keep the names of the previous participants
+	if hp.Territory != 0 {
+		s += fmt.Sprintf("_t%3.2f", hp.Territory)
+	}
+	return s
 }
 
 // NewDefaultHeuristicParameters creates defaultns heuristic parameters
@@ -76,6 +86,7 @@ func NewDefaultHeuristicParameters() HeuristicParameters {
 		WinThreshold:     DefaultWinThreshold,
 		MaxGroups:        DefaultMaxGroups,
 		Groups:           DefaultGroups,
+		Territory:        DefaultTerritory,
 	}
 }
 
@@ -111,12 +122,26 @@ func getCoups() []model.Coup {
 	return coups.([]model.Coup)[:0]
 }
 
+// Evaluator scores the states which are neither won nor lost for the Ally race, in place of the weighted terms of
+// the heuristic parameters
+type Evaluator interface {
+	Evaluate(s *model.State) float64
+	// Name is added to the name of the heuristic
+	Name() string
+}
+
 // Heuristic represents a heuristic
 type Heuristic struct {
 	HeuristicParameters
 
+	// evaluator replaces the terms of the parameters if not nil
+	evaluator Evaluator
+
 	// Used to avoid reallocations when computing a state hash
 	hashBuffer []uint32
+
+	// logger of the search, nil for the default logger
+	logger *logging.Logger
 }
 
 func (h *Heuristic) String() string {
@@ -125,12 +150,35 @@ func (h *Heuristic) String() string {
 
 // ShortString returns a smaller string representation of the heuristic
 func (h *Heuristic) ShortString() string {
+	if h.evaluator != nil {
+		return h.HeuristicParameters.ShortString() + "_" + h.evaluator.Name()
+	}
 	return h.HeuristicParameters.ShortString()
 }
 
 // NewHeuristic creates a new heuristic given parameters
 func NewHeuristic(params HeuristicParameters) Heuristic {
-	return Heuristic{params, make([]uint32, 0, 32)}
+	return Heuristic{HeuristicParameters: params, hashBuffer: make([]uint32, 0, 32)}
+}
+
+// NewHeuristicWithEvaluator creates a heuristic scoring the states with e, only the parameters of the search and of
+// the won and lost states are used
+func NewHeuristicWithEvaluator(params HeuristicParameters, e Evaluator) Heuristic {
+	h := NewHeuristic(params)
+	h.evaluator = e
+	return h
+}
+
+// Evaluator returns the evaluator of the heuristic, nil if it uses the terms of its parameters
+func (h *Heuristic) Evaluator() Evaluator {
+	return h.evaluator
+}
+
+func (h *Heuristic) log() *logging.Logger {
+	if h.logger == nil {
+		return logging.Default()
+	}
+	return h.logger
 }
 
 // randomMove gives a random move among the possible moves for the race Ally
@@ -287,6 +335,53 @@ func scoreMonsterBattle(c1, c2 model.Coordinates, cell1, cell2 model.Cell) (floa
 	return s1 / distance, s2 / distance
 }
 
+// Territory returns the humans each race would capture, as scored by the Territory parameter
+func Territory(s *model.State) (ally, enemy float64) {
+	t := scoreTerritory(s)
+	return t.ally, t.enemy
+}
+
+// scoreTerritory counts the humans each race would capture: a neutral group goes to the race of the closest monster
+// group with enough units to surely convert it, and is shared when both races are at the same distance. The
+// distance is the number of moves, as given by Coordinates.Distance
+func scoreTerritory(s *model.State) scoreCounter {
+	territory := scoreCounter{}
+
+	for c1, cell1 := range s.Grid {
+		if cell1.Race != model.Neutral {
+			continue
+		}
+
+		ally, enemy := math.Inf(1), math.Inf(1)
+		for c2, cell2 := range s.Grid {
+			if cell2.Race == model.Neutral || cell2.Count < cell1.Count {
+				continue
+			}
+			d := c2.Distance(c1)
+			if cell2.Race == model.Ally {
+				ally = math.Min(ally, d)
+			} else {
+				enemy = math.Min(enemy, d)
+			}
+		}
+
+		humans := float64(cell1.Count)
+		switch {
+		case math.IsInf(ally, 1) && math.IsInf(enemy, 1):
+			// Nobody can convert them yet
+		case ally < enemy:
+			territory.ally += humans
+		case enemy < ally:
+			territory.enemy += humans
+		default:
+			territory.ally += humans / 2
+			territory.enemy += humans / 2
+		}
+	}
+
+	return territory
+}
+
 type scoreCounter struct {
 	ally  float64
 	enemy float64
@@ -301,22 +396,38 @@ func (sc *scoreCounter) add(race model.Race, score float64) {
 	}
 }
 
-// scoreState is the heuristic for our IA
-func (h *Heuristic) scoreState(s *model.State) float64 {
+// heuristicTerm is a term of the heuristic, it contributes the difference of its scores times its coefficient
+type heuristicTerm struct {
+	name   string
+	coef   float64
+	scores scoreCounter
+}
+
+func (t heuristicTerm) contribution() float64 {
+	return (t.scores.ally - t.scores.enemy) * t.coef
+}
+
+// stateTerms computes the terms summed by scoreState and detailed by Explain, counts are always the first term.
+// Unless all is set, the terms with a coefficient of 0 or replaced by the evaluator are not computed. When battle is
+// not nil, it is called with every battle considered from a monster group
+func (h *Heuristic) stateTerms(s *model.State, all bool, battle func(c model.Coordinates, pair BattlePair)) [5]heuristicTerm {
 
 	// different counts participating in the heuristic
 	counts := scoreCounter{}
 	battleCounts := scoreCounter{}
 	neutralBattleCounts := scoreCounter{}
 
+	// Avoid computing battles scores if the coefficients are 0 or if they are not used
+	neutralBattles := all || (h.evaluator == nil && h.NeutralBattles != 0)
+	monsterBattles := all || (h.evaluator == nil && h.Battles != 0)
+
 	for c1, cell1 := range s.Grid {
 		if cell1.Race == model.Neutral {
 			continue
 		}
 		counts.add(cell1.Race, float64(cell1.Count))
 
-		// Avoid computing battles scores if the coefficients are 0
-		if h.Battles == 0 && h.NeutralBattles == 0 {
+		if !neutralBattles && !monsterBattles {
 			continue
 		}
 
@@ -327,19 +438,56 @@ func (h *Heuristic) scoreState(s *model.State) float64 {
 			}
 
 			// TODO: try distance power alpha instead of distance power 1, caveat: computations
-			if cell2.Race == model.Neutral && h.NeutralBattles != 0 {
+			if cell2.Race == model.Neutral {
+				if !neutralBattles {
+					continue
+				}
 				// TODO: average here since we can count a battle multiple times, for now we just consider it as multiple opportunities, hence there is no average
-				neutralBattleCounts.add(cell1.Race, scoreNeutralBattle(c1, c2, cell1, cell2))
-			} else if cell2.Race == cell1.Race.Opponent() && h.Battles != 0 {
+				gain := scoreNeutralBattle(c1, c2, cell1, cell2)
+				neutralBattleCounts.add(cell1.Race, gain)
+				if battle != nil {
+					battle(c1, BattlePair{Target: c2, Cell: cell2, Neutral: true, Distance: c1.Distance(c2), Gain: gain})
+				}
+			} else if monsterBattles {
 				// TODO: average here since we can count a battle multiple times, for now we just consider it as multiple opportunities, hence there is no average
+				// Each pair of monster groups is counted from both groups
 				g1, g2 := scoreMonsterBattle(c1, c2, cell1, cell2)
 				battleCounts.add(cell1.Race, g1)
 				battleCounts.add(cell2.Race, g2)
+				if battle != nil {
+					battle(c1, BattlePair{Target: c2, Cell: cell2, Distance: c1.Distance(c2), Gain: g1, Loss: g2})
+				}
 			}
 		}
 	}
 
-	total := 0.
+	var territory scoreCounter
+	if all || (h.evaluator == nil && h.Territory != 0) {
+		territory = scoreTerritory(s)
+	}
+
+	// A term replaced by the evaluator contributes nothing
+	coef := func(c float64) float64 {
+		if h.evaluator != nil {
+			return 0
+		}
+		return c
+	}
+
+	return [5]heuristicTerm{
+		{TermCounts, coef(h.Counts), counts},
+		{TermBattles, coef(h.Battles), battleCounts},
+		{TermNeutralBattles, coef(h.NeutralBattles), neutralBattleCounts},
+		{TermGroups, coef(h.Groups), scoreCounter{ally: float64(s.AlliesGroups), enemy: float64(s.EnemiesGroups)}},
+		{TermTerritory, coef(h.Territory), territory},
+	}
+}
+
+// scoreState is the heuristic for our IA
+func (h *Heuristic) scoreState(s *model.State) float64 {
+
+	terms := h.stateTerms(s, false, nil)
+	counts := terms[0].scores
 
 	cumScore := (s.CumulativeScore * h.CumScore)
 
@@ -351,19 +499,13 @@ func (h *Heuristic) scoreState(s *model.State) float64 {
 		return h.WinScore - cumScore
 	}
 
-	groupsCounts := scoreCounter{ally: float64(s.AlliesGroups), enemy: float64(s.EnemiesGroups)}
-
-	for _, heuristic := range []struct {
-		coef   float64
-		scores scoreCounter
-	}{
-		{h.Counts, counts},
-		{h.Battles, battleCounts},
-		{h.NeutralBattles, neutralBattleCounts},
-		{h.Groups, groupsCounts},
-	} {
-		score := heuristic.scores.ally - heuristic.scores.enemy
-		total += score * heuristic.coef
+	if h.evaluator != nil {
+		return h.evaluator.Evaluate(s) + cumScore
+	}
+
+	total := 0.
+	for _, t := range terms {
+		total += t.contribution()
 	}
 
 	return total + cumScore
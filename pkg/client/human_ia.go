@@ -0,0 +1,177 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
+)
+
+// ANSI escape codes used to color the races
+const (
+	colorReset   = "\033[0m"
+	colorHumans  = "\033[33m"
+	colorAllies  = "\033[34m"
+	colorEnemies = "\033[31m"
+)
+
+const humanHelp = `commands:
+  x y n x y    move n units from (x, y) to (x, y)
+  <enter>, s   send the moves
+  c            cancel the moves
+  h            this help
+`
+
+// HumanIA lets a human play from a terminal: the state is rendered and the moves are read as "x y n x y" lines,
+// they are checked against the rules before being sent
+type HumanIA struct {
+	in    *bufio.Scanner
+	out   io.Writer
+	color bool
+}
+
+var _ IA = &HumanIA{}
+
+func init() {
+	Register("human", "a human playing in the terminal, option color (true) to color the races", func(opts *Options) (IA, error) {
+		return NewHumanIA(os.Stdin, os.Stdout, opts.Bool("color", true)), nil
+	})
+}
+
+// NewHumanIA creates an IA reading the moves from in and rendering the states to out
+func NewHumanIA(in io.Reader, out io.Writer, color bool) *HumanIA {
+	return &HumanIA{in: bufio.NewScanner(in), out: out, color: color}
+}
+
+func (h *HumanIA) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (h *HumanIA) cellRepr(cell model.Cell) string {
+	switch cell.Race {
+	case model.Ally:
+		return h.paint(colorAllies, fmt.Sprintf("%3dA", cell.Count))
+	case model.Enemy:
+		return h.paint(colorEnemies, fmt.Sprintf("%3dE", cell.Count))
+	default:
+		return h.paint(colorHumans, fmt.Sprintf("%3dH", cell.Count))
+	}
+}
+
+// Render renders the state with the coordinates of the cells, our units are A, the enemies E and the humans H
+func (h *HumanIA) Render(state *model.State, coup model.Coup) string {
+	var allies, enemies, humans int
+	for _, cell := range state.Grid {
+		switch cell.Race {
+		case model.Ally:
+			allies += int(cell.Count)
+		case model.Enemy:
+			enemies += int(cell.Count)
+		default:
+			humans += int(cell.Count)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(
+		&b, "%s: %d | %s: %d | %s: %d\n",
+		h.paint(colorAllies, "us"), allies, h.paint(colorEnemies, "enemies"), enemies, h.paint(colorHumans, "humans"), humans,
+	)
+
+	b.WriteString("y\\x")
+	for x := uint8(0); x < state.Width; x++ {
+		fmt.Fprintf(&b, "  %3d  ", x)
+	}
+	b.WriteString("\n")
+	rows := strings.Split(strings.TrimPrefix(state.Render(h.cellRepr), "\n"), "\n")
+	for y, row := range rows {
+		fmt.Fprintf(&b, "%3d %s|\n", y, strings.TrimSuffix(row, "|"))
+	}
+
+	for _, move := range coup {
+		fmt.Fprintf(&b, "move %d units from (%d, %d) to (%d, %d)\n", move.N, move.Start.X, move.Start.Y, move.End.X, move.End.Y)
+	}
+	return b.String()
+}
+
+// parseMove parses a "x y n x y" line
+func parseMove(fields []string) (model.Move, error) {
+	if len(fields) != 5 {
+		return model.Move{}, fmt.Errorf("a move is 5 numbers: x y n x y")
+	}
+	var values [5]uint8
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 8)
+		if err != nil {
+			return model.Move{}, fmt.Errorf("invalid number %q", f)
+		}
+		values[i] = uint8(v)
+	}
+	return model.Move{
+		Start: model.Coordinates{X: values[0], Y: values[1]},
+		N:     values[2],
+		End:   model.Coordinates{X: values[3], Y: values[4]},
+	}, nil
+}
+
+// Play renders the state and reads moves until the human sends a valid coup. If the input ends, the valid moves
+// entered so far are played
+func (h *HumanIA) Play(state *model.State) model.Coup {
+	var coup model.Coup
+	message := "your turn\n"
+	for {
+		if _, err := fmt.Fprintf(h.out, "%s%s> ", h.Render(state, coup), message); err != nil {
+			logging.Default().Error("rendering the state", "err", err)
+		}
+		message = ""
+
+		if !h.in.Scan() {
+			logging.Default().Warn("input closed, playing the moves entered", "moves", len(coup))
+			return coup
+		}
+
+		fields := strings.Fields(h.in.Text())
+		if len(fields) == 0 {
+			fields = []string{"s"}
+		}
+
+		switch fields[0] {
+		case "s":
+			if err := model.ValidateCoup(state, model.Ally, coup); err != nil {
+				message = err.Error() + "\n"
+				continue
+			}
+			return coup
+		case "c":
+			coup = nil
+		case "h":
+			message = humanHelp
+		default:
+			move, err := parseMove(fields)
+			if err != nil {
+				message = fmt.Sprintf("%s\n%s", err, humanHelp)
+				continue
+			}
+			// The move is checked together with the previous ones, they share the units and the cells
+			next := append(append(model.Coup{}, coup...), move)
+			if err := model.ValidateCoup(state, model.Ally, next); err != nil {
+				message = err.Error() + "\n"
+				continue
+			}
+			coup = next
+		}
+	}
+}
+
+// Name of the IA
+func (h *HumanIA) Name() string {
+	return "human"
+}
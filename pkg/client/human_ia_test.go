@@ -0,0 +1,54 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHumanIARender(t *testing.T) {
+	h := NewHumanIA(nil, nil, false)
+	out := h.Render(watchdogState(), model.Coup{{Start: home, N: 2, End: human}})
+
+	assert.Contains(t, out, "us: 4 | enemies: 4 | humans: 0\n")
+	assert.Contains(t, out, "y\\x    0      1      2  \n  0 |   4A |      |      |\n  1 |      |      |      |\n  2 |      |      |   4E |\n")
+	assert.Contains(t, out, "move 2 units from (0, 0) to (1, 1)\n")
+}
+
+func TestHumanIAPlay(t *testing.T) {
+	in := strings.NewReader("foo\n\n0 0 9 1 1\n0 0 2 1 1\nc\n0 0 2 1 1\n0 0 2 0 1\n1 1 1 2 1\n\n")
+	var out bytes.Buffer
+	h := NewHumanIA(in, &out, false)
+
+	coup := h.Play(watchdogState())
+	assert.Equal(t, model.Coup{{Start: home, N: 2, End: human}, {Start: home, N: 2, End: model.Coordinates{X: 0, Y: 1}}}, coup)
+
+	for _, message := range []string{"a move is 5 numbers", "a coup needs at least one move", "only 4 units", "no units of ours"} {
+		assert.Contains(t, out.String(), message)
+	}
+}
+
+func TestHumanIAInputClosed(t *testing.T) {
+	h := NewHumanIA(strings.NewReader("0 0 4 1 0\n"), &bytes.Buffer{}, false)
+	assert.Equal(t, model.Coup{{Start: home, N: 4, End: model.Coordinates{X: 1, Y: 0}}}, h.Play(watchdogState()))
+}
+
+func TestHumanIAOverTCP(t *testing.T) {
+	// We are werewolves, the moves are entered from our point of view
+	h := NewHumanIA(strings.NewReader("0 0 4 1 1\n\n"), &bytes.Buffer{}, false)
+	server, _, done := startPipeClient(t, h)
+	defer server.Close()
+
+	require.NoError(t, server.Start(testGame(true)))
+	moves, err := server.Play()
+	require.NoError(t, err)
+	assert.Equal(t, []model.Move{{Start: home, N: 4, End: human}}, moves)
+
+	require.NoError(t, server.Send(protocol.Bye{}))
+	assert.NoError(t, <-done)
+}
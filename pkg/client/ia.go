@@ -1,8 +1,31 @@
 package client
 
-import "github.com/langorou/langorou/pkg/client/model"
+import (
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
+)
 
 type IA interface {
 	Play(state *model.State) model.Coup
 	Name() string
 }
+
+// Anytime is implemented by the IAs which can give the best coup found so far while they are still searching
+type Anytime interface {
+	IA
+	// PlayAnytime is the same as Play but calls progress with each better coup found
+	PlayAnytime(state *model.State, progress func(coup model.Coup)) model.Coup
+}
+
+// Logged is implemented by the IAs which log, the game gives them a logger with the player, game and turn fields
+// before each turn. SetLogger may be called while the IA is still playing a previous turn
+type Logged interface {
+	SetLogger(l *logging.Logger)
+}
+
+// Searcher is implemented by the IAs searching with a heuristic, which can then be used to analyze positions
+type Searcher interface {
+	IA
+	// Heuristic returns a copy of the heuristic of the IA
+	Heuristic() Heuristic
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/metrics"
+)
+
+// Instrumented is implemented by the IAs feeding the metrics of the player
+type Instrumented interface {
+	SetMetrics(m *PlayerMetrics)
+}
+
+// PlayerMetrics follows the behaviour of the engine over the games, it is fed by the TCPClient, the game and the
+// MinMaxIA. The methods of a nil *PlayerMetrics do nothing
+type PlayerMetrics struct {
+	games        *metrics.Counter
+	moves        *metrics.Counter
+	turn         *metrics.Gauge
+	moveDuration *metrics.Histogram
+	moveAllotted *metrics.Gauge
+	depth        *metrics.Histogram
+	nodes        *metrics.Counter
+	ttSize       *metrics.Gauge
+	battles      map[model.Race]*metrics.Counter
+	population   map[model.Race]*metrics.Gauge
+}
+
+// NewPlayerMetrics registers the metrics of a player in r, labels are name value pairs added to all the metrics to
+// tell apart several players of a process
+func NewPlayerMetrics(r *metrics.Registry, labels ...string) *PlayerMetrics {
+	with := func(pairs ...string) []string {
+		return append(append([]string{}, labels...), pairs...)
+	}
+	return &PlayerMetrics{
+		games: r.Counter("langorou_games_total", "Number of games started.", labels...),
+		moves: r.Counter("langorou_moves_total", "Number of coups sent to the server.", labels...),
+		turn:  r.Gauge("langorou_turn", "Turn of the current game.", labels...),
+		moveDuration: r.Histogram(
+			"langorou_move_duration_seconds", "Time used to choose a coup, from the reception of UPD.",
+			[]float64{0.1, 0.25, 0.5, 1, 1.5, 1.75, 2, 3, 5, 10}, labels...,
+		),
+		moveAllotted: r.Gauge("langorou_move_allotted_seconds", "Time allotted to choose a coup, 0 if there is no deadline.", labels...),
+		depth: r.Histogram(
+			"langorou_search_depth", "Depth of the deepest completed iteration of each search.",
+			[]float64{1, 2, 3, 4, 5, 6, 7, 8, 10, 12, 15, 20}, labels...,
+		),
+		nodes:  r.Counter("langorou_search_nodes_total", "Number of nodes searched by the completed iterations.", labels...),
+		ttSize: r.Gauge("langorou_search_tt_entries", "Number of entries of the transposition table at the end of the last search.", labels...),
+		battles: map[model.Race]*metrics.Counter{
+			model.Neutral: r.Counter("langorou_battles_total", "Number of battles entered by our moves.", with("against", "humans")...),
+			model.Enemy:   r.Counter("langorou_battles_total", "Number of battles entered by our moves.", with("against", "enemy")...),
+		},
+		population: map[model.Race]*metrics.Gauge{
+			model.Neutral: r.Gauge("langorou_population", "Number of units on the grid.", with("race", "humans")...),
+			model.Ally:    r.Gauge("langorou_population", "Number of units on the grid.", with("race", "ally")...),
+			model.Enemy:   r.Gauge("langorou_population", "Number of units on the grid.", with("race", "enemy")...),
+		},
+	}
+}
+
+// gameStarted counts a new game
+func (m *PlayerMetrics) gameStarted() {
+	if m == nil {
+		return
+	}
+	m.games.Inc()
+	m.turn.Set(0)
+}
+
+// observeState updates the population with the state received from the server
+func (m *PlayerMetrics) observeState(state *model.State) {
+	if m == nil {
+		return
+	}
+	counts := map[model.Race]float64{}
+	for _, cell := range state.Grid {
+		counts[cell.Race] += float64(cell.Count)
+	}
+	for race, g := range m.population {
+		g.Set(counts[race])
+	}
+}
+
+// observeMove records a coup played in state during turn, allotted is 0 if there is no deadline
+func (m *PlayerMetrics) observeMove(state *model.State, coup model.Coup, turn int, used time.Duration, allotted time.Duration) {
+	if m == nil {
+		return
+	}
+	m.moves.Inc()
+	m.turn.Set(float64(turn))
+	m.moveDuration.Observe(used.Seconds())
+	m.moveAllotted.Set(allotted.Seconds())
+
+	// Several moves may target the same cell, it is a single battle
+	targets := map[model.Coordinates]bool{}
+	for _, move := range coup {
+		targets[move.End] = true
+	}
+	for target := range targets {
+		cell, ok := state.Grid[target]
+		if !ok || cell.IsEmpty() {
+			continue
+		}
+		if c, ok := m.battles[cell.Race]; ok {
+			c.Inc()
+		}
+	}
+}
+
+// observeSearch records the result of a search
+func (m *PlayerMetrics) observeSearch(eval Evaluation) {
+	if m == nil {
+		return
+	}
+	m.depth.Observe(float64(eval.Depth))
+	m.nodes.Add(float64(eval.Nodes))
+	m.ttSize.Set(float64(eval.TTSize))
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/metrics"
+	"github.com/langorou/langorou/pkg/mockserver"
+	"github.com/langorou/langorou/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// playOneTurn plays a game of one turn with the IA and returns the metrics
+func playOneTurn(t *testing.T, ia IA, deadline MoveDeadline) string {
+	registry := metrics.NewRegistry()
+	server, conn := mockserver.Pipe()
+	defer server.Close()
+	c := NewTCPClientWithConn(conn, "test", ia)
+	c.SetMetrics(NewPlayerMetrics(registry, "player", "p1"))
+	c.SetMoveDeadline(deadline)
+
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+	_, err := server.ExpectName()
+	require.NoError(t, err)
+	require.NoError(t, server.Start(testGame(false)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.Bye{}))
+	require.NoError(t, <-done)
+
+	var b bytes.Buffer
+	require.NoError(t, registry.WriteText(&b))
+	return b.String()
+}
+
+func TestPlayerMetrics(t *testing.T) {
+	ia := &recordingIA{coups: []model.Coup{{{Start: home, N: 4, End: human}}}}
+	out := playOneTurn(t, ia, MoveDeadline{Deadline: time.Second, Margin: 100 * time.Millisecond})
+
+	for _, sample := range []string{
+		`langorou_games_total{player="p1"} 1`,
+		`langorou_moves_total{player="p1"} 1`,
+		`langorou_turn{player="p1"} 1`,
+		`langorou_move_duration_seconds_count{player="p1"} 1`,
+		`langorou_move_allotted_seconds{player="p1"} 0.9`,
+		`langorou_battles_total{player="p1",against="humans"} 1`,
+		`langorou_battles_total{player="p1",against="enemy"} 0`,
+		`langorou_population{player="p1",race="ally"} 4`,
+		`langorou_population{player="p1",race="enemy"} 4`,
+		`langorou_population{player="p1",race="humans"} 2`,
+	} {
+		assert.Contains(t, out, sample+"\n")
+	}
+}
+
+func TestPlayerMetricsSearch(t *testing.T) {
+	out := playOneTurn(t, NewMinMaxIA(20*time.Millisecond), MoveDeadline{})
+
+	assert.Contains(t, out, `langorou_search_depth_count{player="p1"} 1`+"\n")
+	assert.Contains(t, out, `langorou_move_allotted_seconds{player="p1"} 0`+"\n")
+	assert.NotContains(t, out, `langorou_search_nodes_total{player="p1"} 0`+"\n")
+	assert.NotContains(t, out, `langorou_search_tt_entries{player="p1"} 0`+"\n")
+}
@@ -31,6 +31,7 @@ const (
 type transpositionTable struct {
 	t            map[uint64]result
 	hits, misses uint
+	nodes        uint64 // number of nodes searched with the table
 }
 
 func (t *transpositionTable) get(hash uint64, maxDepth uint8) (result, bool) {
@@ -61,45 +62,124 @@ func (t *transpositionTable) save(hash uint64, coup model.Coup, value float64, d
 	t.t[hash] = s
 }
 
+// Evaluation is the outcome of a search for the Ally race
+type Evaluation struct {
+	// Coup is the best coup found
+	Coup model.Coup
+	// Score is the score of the best coup
+	Score float64
+	// Depth is the depth of the deepest completed iteration
+	Depth uint8
+	// Nodes is the number of nodes searched by the completed iterations
+	Nodes uint64
+	// TTSize is the number of entries of the transposition table after the deepest completed iteration
+	TTSize int
+}
+
 func (h *Heuristic) findBestCoupWithTimeout(state *model.State, timeout time.Duration) model.Coup {
+	return h.iterativeDeepening(context.Background(), state, timeout, false, nil).Coup
+}
+
+// SearchWithTimeout searches the best coup for the Ally race until the timeout expires, contrary to
+// findBestCoupWithTimeout it waits for the search to be fully stopped before returning, so the heuristic
+// can safely be reused right after
+func (h *Heuristic) SearchWithTimeout(state *model.State, timeout time.Duration) Evaluation {
+	return h.iterativeDeepening(context.Background(), state, timeout, true, nil)
+}
+
+// SearchDepth searches the best coup for the Ally race at a fixed depth, unlike SearchWithTimeout the result doesn't
+// depend on the speed of the machine
+func (h *Heuristic) SearchDepth(state *model.State, depth uint8) Evaluation {
+	coup, score := h.findBestCoup(state, depth)
+	return Evaluation{Coup: coup, Score: score, Depth: depth}
+}
+
+// iterativeDeepening searches deeper and deeper until the timeout expires or parent is done, progress (if not nil) is
+// called with the result of each completed depth
+func (h *Heuristic) iterativeDeepening(parent context.Context, state *model.State, timeout time.Duration, wait bool, progress func(Evaluation)) Evaluation {
 	// We use time.NewTimer instead of time.After because it's much more precise
 	timer := time.NewTimer(timeout)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
-	results := make(chan model.Coup, 10)
+	results := make(chan Evaluation, 10)
+	done := make(chan struct{})
 
 	go func() {
-		tt := &transpositionTable{map[uint64]result{}, 0, 0}
-		depth := uint8(1)
-		for {
+		defer close(done)
+		tt := &transpositionTable{t: map[uint64]result{}}
+		for depth := uint8(1); depth < math.MaxUint8; depth++ {
+			coup, score := h.alphabeta(ctx, tt, state, model.Ally, negInfinity, posInfinity, 0, depth)
+			// Don't block on a full channel once nobody listens anymore
 			select {
 			case <-ctx.Done():
 				return
-			default:
-				coup, _ := h.alphabeta(ctx, tt, state, model.Ally, negInfinity, posInfinity, 0, depth)
-				results <- coup
-				depth += 1
+			case results <- Evaluation{Coup: coup, Score: score, Depth: depth, Nodes: tt.nodes, TTSize: len(tt.t)}:
 			}
 		}
 	}()
 
 	// Init with a random move just in case even depth 1 does not complete
-	result := h.randomMove(state)
+	result := Evaluation{Coup: h.randomMove(state)}
+	if progress != nil {
+		progress(result)
+	}
 	for {
 		select {
 		case <-timer.C:
+		case <-parent.Done():
 			timer.Stop()
-			return result
-		case coup := <-results:
-			result = coup
+		case eval := <-results:
+			result = eval
+			if progress != nil {
+				progress(result)
+			}
+			continue
+		}
+
+		if wait {
+			cancel()
+			<-done
 		}
+		h.log().Debug("search stopped", "timeout", timeout, "depth", result.Depth, "score", result.Score, "coup", result.Coup)
+		return result
 	}
 }
 
+// EvaluateCoup computes the expected score of playing coup for the Ally race on state, by searching every potential
+// outcome deeper and deeper up to maxDepth (the root counting as depth 0, like in findBestCoup) until ctx is done. It
+// returns the score at the deepest completed depth, depth 1 is always completed
+func (h *Heuristic) EvaluateCoup(ctx context.Context, state *model.State, coup model.Coup, maxDepth uint8) (score float64, depth uint8) {
+	tt := &transpositionTable{t: map[uint64]result{}}
+
+	// ApplyCoup sorts the coup, don't modify the caller's one
+	coup = append(model.Coup{}, coup...)
+	outcomes := state.ApplyCoup(model.Ally, coup, h.WinThreshold)
+
+	for d := uint8(1); d <= maxDepth; d++ {
+		searchCtx := ctx
+		if d == 1 {
+			searchCtx = context.Background()
+		}
+
+		tmpScore := 0.
+		for _, outcome := range outcomes {
+			_, outcomeScore := h.alphabeta(searchCtx, tt, outcome.State, model.Enemy, negInfinity, posInfinity, 1, d)
+			tmpScore += outcomeScore * outcome.P
+		}
+		// An interrupted search is meaningless
+		if searchCtx.Err() != nil {
+			break
+		}
+		score, depth = tmpScore, d
+	}
+
+	return score, depth
+}
+
 func (h *Heuristic) findBestCoup(state *model.State, maxDepth uint8) (coup model.Coup, score float64) {
 	ctx := context.Background()
-	tt := &transpositionTable{map[uint64]result{}, 0, 0}
+	tt := &transpositionTable{t: map[uint64]result{}}
 
 	for depth := uint8(1); depth <= maxDepth; depth++ {
 		coup, score = h.alphabeta(ctx, tt, state, model.Ally, negInfinity, posInfinity, 0, depth)
@@ -117,6 +197,7 @@ func (h *Heuristic) alphabeta(ctx context.Context, tt *transpositionTable, state
 		return bestCoup, 0 // This won't be used so we can return anything
 	default:
 	}
+	tt.nodes++
 
 	hash := state.Hash(race, h.hashBuffer)
 
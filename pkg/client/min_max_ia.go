@@ -1,23 +1,110 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
 )
 
 type MinMaxIA struct {
 	timeout   time.Duration
 	heuristic Heuristic
+
+	mu      sync.Mutex // protects logger, metrics, rand and game, which may be used while a previous turn is still searched
+	logger  *logging.Logger
+	metrics *PlayerMetrics
+
+	// book is consulted before searching if not nil, rand chooses between its coups
+	book *Book
+	rand *rand.Rand
+
+	// endgame bounds the positions given to the endgame solver before searching
+	endgame EndgameLimits
+
+	// game is done once the game is over, it stops the searches still running, see Reset
+	game    context.Context
+	endGame context.CancelFunc
+}
+
+var _ Anytime = &MinMaxIA{}
+var _ Logged = &MinMaxIA{}
+var _ Instrumented = &MinMaxIA{}
+var _ Searcher = &MinMaxIA{}
+var _ Resetter = &MinMaxIA{}
+
+// DefaultMinMaxTimeout is the time budget of the min max IA created from a spec without timeout
+const DefaultMinMaxTimeout = time.Second
+
+func init() {
+	Register(
+		"minmax",
+		"min max with iterative deepening, options: timeout (1s), book (path of an opening book), the limits of the endgame solver endgame_groups (0, disabled), endgame_units (24) and endgame_plies (10), and the heuristic parameters counts, battles, neutral_battles, cum_score, win_score, lose_over_win_ratio, win_threshold, max_groups, groups and territory",
+		func(opts *Options) (IA, error) {
+			timeout := opts.Duration("timeout", DefaultMinMaxTimeout)
+			if timeout <= 0 {
+				return nil, fmt.Errorf("timeout should be positive, got %s", timeout)
+			}
+			ia := NewMinMaxIAP(timeout, HeuristicParametersFromOptions(opts, NewDefaultHeuristicParameters()))
+			ia.SetEndgameLimits(EndgameLimitsFromOptions(opts, NewDefaultEndgameLimits()))
+			if err := SetBookFromOptions(ia, opts); err != nil {
+				return nil, err
+			}
+			return ia, nil
+		},
+	)
+}
+
+// SetBookFromOptions gives to ia the opening book at the path of the book option of a spec, if any
+func SetBookFromOptions(ia *MinMaxIA, opts *Options) error {
+	path := opts.String("book", "")
+	if path == "" {
+		return nil
+	}
+	book, err := LoadBook(path)
+	if err != nil {
+		return err
+	}
+	ia.SetBook(book)
+	return nil
+}
+
+// EndgameLimitsFromOptions overrides the limits of the endgame solver given in the options of a spec:
+// endgame_groups, endgame_units and endgame_plies
+func EndgameLimitsFromOptions(opts *Options, def EndgameLimits) EndgameLimits {
+	return EndgameLimits{
+		Groups: opts.Int("endgame_groups", def.Groups),
+		Units:  opts.Int("endgame_units", def.Units),
+		Plies:  opts.Uint8("endgame_plies", def.Plies),
+	}
 }
 
-var _ IA = &MinMaxIA{}
+// HeuristicParametersFromOptions overrides the parameters given in the options of a spec, the keys are the names of
+// the parameters in snake case
+func HeuristicParametersFromOptions(opts *Options, def HeuristicParameters) HeuristicParameters {
+	return HeuristicParameters{
+		Counts:           opts.Float("counts", def.Counts),
+		Battles:          opts.Float("battles", def.Battles),
+		NeutralBattles:   opts.Float("neutral_battles", def.NeutralBattles),
+		CumScore:         opts.Float("cum_score", def.CumScore),
+		WinScore:         opts.Float("win_score", def.WinScore),
+		LoseOverWinRatio: opts.Float("lose_over_win_ratio", def.LoseOverWinRatio),
+		WinThreshold:     opts.Float("win_threshold", def.WinThreshold),
+		MaxGroups:        opts.Uint8("max_groups", def.MaxGroups),
+		Groups:           opts.Float("groups", def.Groups),
+		Territory:        opts.Float("territory", def.Territory),
+	}
+}
 
 func NewMinMaxIA(timeout time.Duration) *MinMaxIA {
 	return &MinMaxIA{
 		timeout:   timeout,
 		heuristic: NewHeuristic(NewDefaultHeuristicParameters()),
+		endgame:   NewDefaultEndgameLimits(),
 	}
 }
 
@@ -25,11 +112,167 @@ func NewMinMaxIAP(timeout time.Duration, params HeuristicParameters) *MinMaxIA {
 	return &MinMaxIA{
 		timeout:   timeout,
 		heuristic: NewHeuristic(params),
+		endgame:   NewDefaultEndgameLimits(),
+	}
+}
+
+// NewMinMaxIAWithEvaluator creates a min max IA scoring the states with e, see NewHeuristicWithEvaluator
+func NewMinMaxIAWithEvaluator(timeout time.Duration, params HeuristicParameters, e Evaluator) *MinMaxIA {
+	return &MinMaxIA{
+		timeout:   timeout,
+		heuristic: NewHeuristicWithEvaluator(params, e),
+		endgame:   NewDefaultEndgameLimits(),
 	}
 }
 
+// SetLogger implements Logged, the logger is used by the next searches
+func (m *MinMaxIA) SetLogger(l *logging.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = l
+}
+
+// SetMetrics implements Instrumented, the depth, nodes and transposition table size of each search are recorded
+func (m *MinMaxIA) SetMetrics(pm *PlayerMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics = pm
+}
+
+// SetBook sets the opening book played before searching, the coups of a position are chosen at random in proportion
+// to their weights. A nil book disables it
+func (m *MinMaxIA) SetBook(b *Book) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.book = b
+	if m.rand == nil {
+		m.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+}
+
+// SetEndgameLimits sets the positions solved exactly before searching, see SolveEndgame. The solver is
+// given a quarter of the time budget since a group able to flee on an open map keeps the outcome unknown, the coup
+// found is played if it is known to be the best, else the search gets the time left. It is skipped when an enemy group
+// is out of reach of the Ally race within the plies of the limits. Limits with no groups disable it
+func (m *MinMaxIA) SetEndgameLimits(l EndgameLimits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endgame = l
+}
+
+// solveEndgame returns the coup of the endgame solver if it is known to be the best, else the time left to search
+func (m *MinMaxIA) solveEndgame(game context.Context, h *Heuristic, state *model.State, pm *PlayerMetrics) (model.Coup, bool, time.Duration) {
+	m.mu.Lock()
+	limits := m.endgame
+	m.mu.Unlock()
+	if !limits.Contains(state) || !limits.InReach(state) {
+		return nil, false, m.timeout
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(game, m.timeout/4)
+	defer cancel()
+	res, ok := SolveEndgame(ctx, state.Copy(false), limits)
+	if !ok || !res.Solved() {
+		return nil, false, m.timeout - time.Since(start)
+	}
+
+	h.log().Debug("coup played by the endgame solver", "coup", res.Coup, "win", res.Win, "lose", res.Lose, "draw", res.Draw, "plies", res.Plies, "nodes", res.Nodes)
+	pm.observeSearch(Evaluation{Coup: res.Coup, Depth: res.Plies, Nodes: res.Nodes})
+	return res.Coup, true, 0
+}
+
+// Reset implements Resetter, the searches of the previous game still running, like one given up by the Watchdog,
+// are stopped instead of competing with the next game
+func (m *MinMaxIA) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.endGame != nil {
+		m.endGame()
+	}
+	m.game, m.endGame = context.WithCancel(context.Background())
+}
+
+// gameContext returns the context of the searches of the current game
+func (m *MinMaxIA) gameContext() context.Context {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.game == nil {
+		m.game, m.endGame = context.WithCancel(context.Background())
+	}
+	return m.game
+}
+
+// bookCoup returns the coup of the book for state, if any
+func (m *MinMaxIA) bookCoup(state *model.State) (model.Coup, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.book == nil {
+		return nil, false
+	}
+	coup, ok := m.book.Choose(state, m.rand)
+	if ok {
+		log := m.logger
+		if log == nil {
+			log = logging.Default()
+		}
+		log.Debug("coup played from the opening book", "coup", coup)
+	}
+	return coup, ok
+}
+
+// search returns the heuristic with the current logger, and the current metrics
+func (m *MinMaxIA) search() (Heuristic, *PlayerMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.heuristic
+	h.logger = m.logger
+	return h, m.metrics
+}
+
 func (m *MinMaxIA) Play(state *model.State) model.Coup {
-	return m.heuristic.findBestCoupWithTimeout(state.Copy(false), m.timeout)
+	if coup, ok := m.bookCoup(state); ok {
+		return coup
+	}
+	h, pm := m.search()
+	game := m.gameContext()
+	coup, ok, timeout := m.solveEndgame(game, &h, state, pm)
+	if ok {
+		return coup
+	}
+	eval := h.iterativeDeepening(game, state.Copy(false), timeout, false, nil)
+	pm.observeSearch(eval)
+	return eval.Coup
+}
+
+// PlayAnytime implements Anytime, progress is called with the result of each depth of the iterative deepening
+func (m *MinMaxIA) PlayAnytime(state *model.State, progress func(coup model.Coup)) model.Coup {
+	if coup, ok := m.bookCoup(state); ok {
+		progress(coup)
+		return coup
+	}
+	h, pm := m.search()
+	game := m.gameContext()
+	coup, ok, timeout := m.solveEndgame(game, &h, state, pm)
+	if ok {
+		progress(coup)
+		return coup
+	}
+	eval := h.iterativeDeepening(game, state.Copy(false), timeout, false, func(eval Evaluation) {
+		progress(eval.Coup)
+	})
+	pm.observeSearch(eval)
+	return eval.Coup
+}
+
+// Heuristic returns a copy of the heuristic of the IA
+func (m *MinMaxIA) Heuristic() Heuristic {
+	return NewHeuristicWithEvaluator(m.heuristic.HeuristicParameters, m.heuristic.evaluator)
+}
+
+// Timeout returns the time budget of the IA
+func (m *MinMaxIA) Timeout() time.Duration {
+	return m.timeout
 }
 
 func (m *MinMaxIA) Name() string {
@@ -492,3 +492,30 @@ func TestGenerateCoupsDifferentStartAndEndCells(t *testing.T) {
 	coups := testHeuristic.generateCoups(startState, model.Ally)
 	assert.Len(t, coups, 10)
 }
+
+func TestScoreTerritory(t *testing.T) {
+	s := model.NewState(10, 10)
+	s.SetCell(model.Coordinates{X: 0, Y: 0}, model.Ally, 10)
+	s.SetCell(model.Coordinates{X: 9, Y: 9}, model.Enemy, 6)
+	// Closer to the allies
+	s.SetCell(model.Coordinates{X: 2, Y: 3}, model.Neutral, 4)
+	// Closer to the enemies, which are too few to convert them, so it goes to the allies
+	s.SetCell(model.Coordinates{X: 8, Y: 7}, model.Neutral, 8)
+	// Closer to the enemies
+	s.SetCell(model.Coordinates{X: 7, Y: 9}, model.Neutral, 5)
+	// As close to both, it is shared
+	s.SetCell(model.Coordinates{X: 5, Y: 4}, model.Neutral, 2)
+	// Too big for both
+	s.SetCell(model.Coordinates{X: 5, Y: 0}, model.Neutral, 20)
+
+	assert.Equal(t, scoreCounter{ally: 4 + 8 + 1, enemy: 5 + 1}, scoreTerritory(s))
+
+	// Only counted when its coefficient isn't 0
+	params := NewDefaultHeuristicParameters()
+	without := NewHeuristic(params)
+	params.Territory = 0.5
+	with := NewHeuristic(params)
+	assert.InDelta(t, without.scoreState(s)+0.5*(13-6), with.scoreState(s), 1e-9)
+
+	assert.Equal(t, without.ShortString()+"_t0.50", with.ShortString())
+}
@@ -25,6 +25,14 @@ func (c1 *Coordinates) Distance(c2 Coordinates) float64 {
 	return float64(uint8Max(uint8Sub(c1.X, c2.X), uint8Sub(c1.Y, c2.Y)))
 }
 
+// LessCoordinates orders coordinates by row then by column
+func LessCoordinates(c1, c2 Coordinates) bool {
+	if c1.Y != c2.Y {
+		return c1.Y < c2.Y
+	}
+	return c1.X < c2.X
+}
+
 // Changes is an update of the board sent by the server
 type Changes struct {
 	Coords  Coordinates
@@ -0,0 +1,48 @@
+package model
+
+import "fmt"
+
+// ValidateCoup checks that a coup follows the rules of the game for the given race:
+//  1. there is at least one move
+//  2. each move starts from a cell of the race and moves at least one unit
+//  3. each move ends in the grid, on one of the 8 neighbouring cells
+//  4. the moves from a cell don't take more units than it holds
+//  5. a cell can't be both the start and the end of moves
+func ValidateCoup(s *State, race Race, coup Coup) error {
+	if len(coup) == 0 {
+		return fmt.Errorf("a coup needs at least one move")
+	}
+
+	moved := map[Coordinates]int{}
+	starts := map[Coordinates]bool{}
+	ends := map[Coordinates]bool{}
+	for _, move := range coup {
+		cell, ok := s.Grid[move.Start]
+		if !ok || cell.Race != race || cell.IsEmpty() {
+			return fmt.Errorf("move %+v: no units of ours at the start cell", move)
+		}
+		if move.N == 0 {
+			return fmt.Errorf("move %+v: at least one unit must move", move)
+		}
+		if move.End.X >= s.Width || move.End.Y >= s.Height {
+			return fmt.Errorf("move %+v: the end cell is out of the %dx%d grid", move, s.Height, s.Width)
+		}
+		if move.Start.Distance(move.End) != 1 {
+			return fmt.Errorf("move %+v: the end cell must be next to the start cell", move)
+		}
+
+		moved[move.Start] += int(move.N)
+		if moved[move.Start] > int(cell.Count) {
+			return fmt.Errorf("move %+v: only %d units at the start cell", move, cell.Count)
+		}
+		starts[move.Start] = true
+		ends[move.End] = true
+	}
+
+	for c := range starts {
+		if ends[c] {
+			return fmt.Errorf("cell %+v can't be both the start and the end of moves", c)
+		}
+	}
+	return nil
+}
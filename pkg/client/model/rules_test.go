@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCoup(t *testing.T) {
+	s := NewState(3, 3)
+	s.SetCell(Coordinates{X: 0, Y: 0}, Ally, 6)
+	s.SetCell(Coordinates{X: 1, Y: 1}, Neutral, 2)
+	s.SetCell(Coordinates{X: 2, Y: 2}, Enemy, 4)
+	s.SetCell(Coordinates{X: 1, Y: 0}, Ally, 2)
+
+	home := Coordinates{X: 0, Y: 0}
+	cases := []struct {
+		name string
+		coup Coup
+		err  string
+	}{
+		{"single move", Coup{{Start: home, N: 6, End: Coordinates{X: 1, Y: 1}}}, ""},
+		{"split", Coup{{Start: home, N: 3, End: Coordinates{X: 1, Y: 0}}, {Start: home, N: 3, End: Coordinates{X: 0, Y: 1}}}, ""},
+		{"two groups", Coup{{Start: home, N: 6, End: Coordinates{X: 0, Y: 1}}, {Start: Coordinates{X: 1, Y: 0}, N: 2, End: Coordinates{X: 2, Y: 0}}}, ""},
+		{"empty", Coup{}, "at least one move"},
+		{"not ours", Coup{{Start: Coordinates{X: 2, Y: 2}, N: 4, End: Coordinates{X: 2, Y: 1}}}, "no units of ours"},
+		{"empty cell", Coup{{Start: Coordinates{X: 2, Y: 0}, N: 1, End: Coordinates{X: 2, Y: 1}}}, "no units of ours"},
+		{"no unit", Coup{{Start: home, N: 0, End: Coordinates{X: 1, Y: 0}}}, "at least one unit"},
+		{"out of the grid", Coup{{Start: home, N: 6, End: Coordinates{X: 255, Y: 0}}}, "out of the 3x3 grid"},
+		{"too far", Coup{{Start: home, N: 6, End: Coordinates{X: 2, Y: 0}}}, "next to the start cell"},
+		{"same cell", Coup{{Start: home, N: 6, End: home}}, "next to the start cell"},
+		{"too many units", Coup{{Start: home, N: 4, End: Coordinates{X: 1, Y: 0}}, {Start: home, N: 4, End: Coordinates{X: 0, Y: 1}}}, "only 6 units"},
+		{
+			"start and end",
+			Coup{{Start: home, N: 3, End: Coordinates{X: 1, Y: 0}}, {Start: Coordinates{X: 1, Y: 0}, N: 1, End: Coordinates{X: 2, Y: 0}}},
+			"both the start and the end",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateCoup(s, Ally, c.coup)
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), c.err)
+			}
+		})
+	}
+}
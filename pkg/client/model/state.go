@@ -116,15 +116,23 @@ func (s State) PPrint() {
 }
 
 func (s State) String() string {
+	raceRepr := []string{"N", "A", "E"}
+	return s.Render(func(cell Cell) string {
+		return fmt.Sprintf("%3.d%s", cell.Count, raceRepr[cell.Race])
+	})
+}
+
+// Render renders the grid like String does, cellRepr gives the representation of each non empty cell,
+// it should be 4 characters wide (not counting invisible characters like terminal colors)
+func (s State) Render(cellRepr func(cell Cell) string) string {
 	rows := make([]string, s.Height)
 
-	raceRepr := []string{"N", "A", "E"}
 	for row := uint8(0); row < s.Height; row++ {
 		for col := uint8(0); col < s.Width; col++ {
 			coord := Coordinates{X: col, Y: row}
 			cell, ok := s.Grid[coord]
 			if ok && !cell.IsEmpty() {
-				rows[row] += fmt.Sprintf("| %3.d%s ", cell.Count, raceRepr[cell.Race])
+				rows[row] += "| " + cellRepr(cell) + " "
 			} else {
 				rows[row] += "|      "
 			}
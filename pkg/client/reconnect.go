@@ -0,0 +1,87 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/langorou/langorou/pkg/logging"
+)
+
+const (
+	// DefaultKeepAlive is the period of the TCP keepalive probes, they detect a server which vanished without
+	// closing the connection
+	DefaultKeepAlive = 15 * time.Second
+	// DefaultDialTimeout is the maximum time to establish a connection
+	DefaultDialTimeout = 5 * time.Second
+)
+
+// Reconnect configures how a player reconnects when the connection to the server drops
+type Reconnect struct {
+	// MaxRetries is the number of consecutive failed attempts before giving up, negative to retry forever
+	MaxRetries int
+	// MinBackoff is the wait before the first retry, it doubles at each new failure up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NewDefaultReconnect returns a policy retrying 10 times, waiting from 500ms to 30s between the attempts
+func NewDefaultReconnect() Reconnect {
+	return Reconnect{MaxRetries: 10, MinBackoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second}
+}
+
+// backoff returns the wait before the given retry, starting at 0
+func (r Reconnect) backoff(retry int) time.Duration {
+	wait := r.MinBackoff
+	for i := 0; i < retry && wait < r.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > r.MaxBackoff {
+		wait = r.MaxBackoff
+	}
+	return wait
+}
+
+// dial connects to the server with TCP keepalive enabled
+func dial(addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: DefaultDialTimeout, KeepAlive: DefaultKeepAlive}
+	return d.Dial("tcp", addr)
+}
+
+// PlayWithReconnect plays on the server until it says bye. When the connection can't be established or drops, it
+// connects again after a backoff, sends our name again and plays the next games, the game in progress is lost. The
+// retries are counted again from 0 once a game was started on a connection. setup (if not nil) configures each new
+// client before it starts, to set its move deadline or its metrics.
+func PlayWithReconnect(addr string, name string, ia IA, r Reconnect, setup func(c *TCPClient)) error {
+	log := logging.Default().With("player", name)
+	retries := 0
+	for {
+		c, err := NewTCPClient(addr, name, ia)
+		if err == nil {
+			if setup != nil {
+				setup(&c)
+			}
+			err = c.Start()
+			c.Close()
+			if err == nil {
+				return nil
+			}
+			if c.games > 0 {
+				// The connection worked, the server was probably restarted
+				retries = 0
+			}
+			// The game in progress is lost, the IA may keep information about it
+			if res, ok := ia.(Resetter); ok {
+				res.Reset()
+			}
+		}
+
+		if r.MaxRetries >= 0 && retries >= r.MaxRetries {
+			return fmt.Errorf("giving up after %d retries: %s", retries, err)
+		}
+		wait := r.backoff(retries)
+		retries++
+		log.Warn("connection failed", "addr", addr, "err", err, "retry", retries, "wait", wait)
+		time.Sleep(wait)
+	}
+}
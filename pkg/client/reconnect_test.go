@@ -0,0 +1,69 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/mockserver"
+	"github.com/langorou/langorou/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	r := Reconnect{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	expected := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for retry, wait := range expected {
+		assert.Equal(t, wait*time.Millisecond, r.backoff(retry), "retry %d", retry)
+	}
+}
+
+func TestPlayWithReconnect(t *testing.T) {
+	server, err := mockserver.Listen()
+	require.NoError(t, err)
+	defer server.Close()
+
+	ia := &recordingIA{}
+	done := make(chan error, 1)
+	go func() {
+		done <- PlayWithReconnect(server.Addr(), "test", ia, Reconnect{MaxRetries: 3, MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}, nil)
+	}()
+
+	// The connection drops in the middle of the first game
+	require.NoError(t, server.Accept())
+	_, err = server.ExpectName()
+	require.NoError(t, err)
+	require.NoError(t, server.Start(testGame(false)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Disconnect())
+
+	// The player connects again, sends its name and plays the next game
+	require.NoError(t, server.Accept())
+	name, err := server.ExpectName()
+	require.NoError(t, err)
+	assert.Equal(t, "test", name)
+	require.NoError(t, server.Start(testGame(true)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.Bye{}))
+
+	require.NoError(t, <-done)
+	assert.Len(t, ia.states, 2)
+	assert.Equal(t, 1, ia.resets)
+}
+
+func TestPlayWithReconnectGivesUp(t *testing.T) {
+	// Nobody listens on this address anymore
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	start := time.Now()
+	err = PlayWithReconnect(addr, "test", &recordingIA{}, Reconnect{MaxRetries: 2, MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "giving up after 2 retries")
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+}
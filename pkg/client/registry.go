@@ -0,0 +1,245 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options are the key=value options of an IA spec. The constructors read them with the typed getters, which record
+// the first invalid value, and Err reports it along with the keys which were not read
+type Options struct {
+	values map[string]string
+	used   map[string]bool
+	err    error
+}
+
+// NewOptions wraps options given by key
+func NewOptions(values map[string]string) *Options {
+	if values == nil {
+		values = map[string]string{}
+	}
+	return &Options{values: values, used: map[string]bool{}}
+}
+
+// lookup returns the value of key and marks it as used
+func (o *Options) lookup(key string) (string, bool) {
+	o.used[key] = true
+	v, ok := o.values[key]
+	return v, ok
+}
+
+func (o *Options) fail(key, value string, err error) {
+	if o.err == nil {
+		o.err = fmt.Errorf("invalid value %q for %s: %s", value, key, err)
+	}
+}
+
+// String returns the value of key, def if it is absent
+func (o *Options) String(key, def string) string {
+	if v, ok := o.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+// Duration returns the value of key parsed like "1600ms", def if it is absent
+func (o *Options) Duration(key string, def time.Duration) time.Duration {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		o.fail(key, v, err)
+		return def
+	}
+	return d
+}
+
+// Float returns the value of key as a float, def if it is absent
+func (o *Options) Float(key string, def float64) float64 {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		o.fail(key, v, err)
+		return def
+	}
+	return f
+}
+
+// Int returns the value of key as an int, def if it is absent
+func (o *Options) Int(key string, def int) int {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		o.fail(key, v, err)
+		return def
+	}
+	return i
+}
+
+// Uint8 returns the value of key as an uint8, def if it is absent
+func (o *Options) Uint8(key string, def uint8) uint8 {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	u, err := strconv.ParseUint(v, 10, 8)
+	if err != nil {
+		o.fail(key, v, err)
+		return def
+	}
+	return uint8(u)
+}
+
+// Bool returns the value of key as a bool, def if it is absent
+func (o *Options) Bool(key string, def bool) bool {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		o.fail(key, v, err)
+		return def
+	}
+	return b
+}
+
+// Err returns the first invalid value, or an error listing the unknown keys
+func (o *Options) Err() error {
+	if o.err != nil {
+		return o.err
+	}
+	var unknown []string
+	for key := range o.values {
+		if !o.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Constructor creates an IA from its options
+type Constructor func(opts *Options) (IA, error)
+
+type registration struct {
+	usage       string
+	constructor Constructor
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registration{}
+)
+
+// Register makes an IA available by name to NewIA, usage describes its options. It is meant to be called from the
+// init function of the package implementing the IA and panics if the name is already taken
+func Register(name string, usage string, c Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if strings.ContainsAny(name, ":,=") {
+		panic(fmt.Sprintf("client: invalid IA name %q", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("client: IA %s registered twice", name))
+	}
+	registry[name] = registration{usage: usage, constructor: c}
+}
+
+// Registered returns the names of the registered IAs, sorted
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Usage describes the registered IAs and their options, one per line
+func Usage() string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s: %s\n", name, registry[name].usage)
+	}
+	return b.String()
+}
+
+// ParseSpec parses a spec like "minmax:timeout=1s,battles=0.02" or "dumb" into the name of the IA and its options
+func ParseSpec(spec string) (string, map[string]string, error) {
+	name, rest := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		name, rest = spec[:i], spec[i+1:]
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid IA spec %q: missing name", spec)
+	}
+
+	opts := map[string]string{}
+	if rest == "" {
+		return name, opts, nil
+	}
+	for _, kv := range strings.Split(rest, ",") {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return "", nil, fmt.Errorf("invalid IA spec %q: option %q should be key=value", spec, kv)
+		}
+		key := kv[:i]
+		if _, ok := opts[key]; ok {
+			return "", nil, fmt.Errorf("invalid IA spec %q: option %s given twice", spec, key)
+		}
+		opts[key] = kv[i+1:]
+	}
+	return name, opts, nil
+}
+
+// NewIA creates an IA from a spec like "minmax:timeout=1s,battles=0.02" or "dumb", the IAs available are the
+// registered ones
+func NewIA(spec string) (IA, error) {
+	name, values, err := ParseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	registryMu.RLock()
+	r, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown IA %q, should be one of %s", name, strings.Join(Registered(), ", "))
+	}
+
+	opts := NewOptions(values)
+	ia, err := r.constructor(opts)
+	if err == nil {
+		err = opts.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("IA %s: %s", name, err)
+	}
+	return ia, nil
+}
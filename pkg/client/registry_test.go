@@ -0,0 +1,79 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpec(t *testing.T) {
+	name, opts, err := ParseSpec("minmax:timeout=1s,battles=0.02")
+	require.NoError(t, err)
+	assert.Equal(t, "minmax", name)
+	assert.Equal(t, map[string]string{"timeout": "1s", "battles": "0.02"}, opts)
+
+	name, opts, err = ParseSpec("remote:url=http://localhost:8000/ia")
+	require.NoError(t, err)
+	assert.Equal(t, "remote", name)
+	assert.Equal(t, map[string]string{"url": "http://localhost:8000/ia"}, opts)
+
+	name, opts, err = ParseSpec("dumb")
+	require.NoError(t, err)
+	assert.Equal(t, "dumb", name)
+	assert.Empty(t, opts)
+
+	for _, spec := range []string{"", ":timeout=1s", "minmax:timeout", "minmax:=1s", "minmax:timeout=1s,", "minmax:timeout=1s,timeout=2s"} {
+		_, _, err := ParseSpec(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNewIA(t *testing.T) {
+	ia, err := NewIA("minmax:timeout=250ms,battles=0.5,max_groups=3")
+	require.NoError(t, err)
+	require.IsType(t, &MinMaxIA{}, ia)
+	minmax := ia.(*MinMaxIA)
+	assert.Equal(t, 250*time.Millisecond, minmax.Timeout())
+
+	params := NewDefaultHeuristicParameters()
+	params.Battles = 0.5
+	params.MaxGroups = 3
+	assert.Equal(t, params, minmax.Heuristic().HeuristicParameters)
+
+	ia, err = NewIA("minmax")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMinMaxTimeout, ia.(*MinMaxIA).Timeout())
+
+	ia, err = NewIA("dumb")
+	require.NoError(t, err)
+	assert.IsType(t, &DumbIA{}, ia)
+
+	errors := map[string]string{
+		"alphazero":                  "unknown IA \"alphazero\"",
+		"dumb:depth=3":               "unknown options depth",
+		"minmax:timeout=1s,btles=2":  "unknown options btles",
+		"minmax:timeout=fast":        "invalid value \"fast\" for timeout",
+		"minmax:max_groups=300":      "invalid value \"300\" for max_groups",
+		"minmax:timeout=-1s":         "timeout should be positive",
+		"human:color=sometimes":      "invalid value \"sometimes\" for color",
+		"minmax:timeout=1s:battles=": "invalid value \"1s:battles=\" for timeout",
+	}
+	for spec, msg := range errors {
+		_, err := NewIA(spec)
+		if assert.Error(t, err, spec) {
+			assert.Contains(t, err.Error(), msg, spec)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, name := range []string{"dumb", "human", "minmax"} {
+		assert.Contains(t, Registered(), name)
+		assert.Contains(t, Usage(), "  "+name+": ")
+	}
+
+	assert.Panics(t, func() { Register("dumb", "", nil) })
+	assert.Panics(t, func() { Register("min:max", "", nil) })
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrServerBye is returned by Init when the server said bye instead of starting a game
+var ErrServerBye = errors.New("server said bye")
+
+// sessionState is the position of the client in a session, a session is made of several consecutive games on the
+// same connection: SET, HUM, HME and MAP start a game, then UPD are received until END, and BYE ends the session
+type sessionState int
+
+const (
+	waitingSet sessionState = iota
+	waitingHum
+	waitingHme
+	waitingMap
+	playing
+	closed
+)
+
+func (s sessionState) String() string {
+	switch s {
+	case waitingSet:
+		return "waiting for a new game"
+	case waitingHum:
+		return "waiting for the humans"
+	case waitingHme:
+		return "waiting for our starting position"
+	case waitingMap:
+		return "waiting for the map"
+	case playing:
+		return "playing"
+	case closed:
+		return "closed"
+	}
+	return fmt.Sprintf("unknown state %d", int(s))
+}
+
+// next returns the state of the session after receiving cmd, or an error if cmd is not expected
+func (s sessionState) next(cmd ServerCmd) (sessionState, error) {
+	if cmd == BYE && s != closed {
+		return closed, nil
+	}
+
+	switch {
+	case s == waitingSet && cmd == SET:
+		return waitingHum, nil
+	case s == waitingHum && cmd == HUM:
+		return waitingHme, nil
+	case s == waitingHme && cmd == HME:
+		return waitingMap, nil
+	case s == waitingMap && cmd == MAP:
+		return playing, nil
+	case s == playing && cmd == UPD:
+		return playing, nil
+	case s == playing && cmd == END:
+		return waitingSet, nil
+	}
+
+	return s, fmt.Errorf("unexpected command %s while %s", cmd, s)
+}
+
+// Resetter is implemented by the IAs keeping information or work in progress between two turns, Reset is called
+// before a new game starts
+type Resetter interface {
+	Reset()
+}
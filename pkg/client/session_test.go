@@ -0,0 +1,113 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSeveralGames(t *testing.T) {
+	ia := &recordingIA{}
+	server, c, done := startPipeClient(t, ia)
+	defer server.Close()
+
+	require.NoError(t, server.Start(testGame(false)))
+	_, err := server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.End{}))
+
+	require.NoError(t, server.Start(testGame(true)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.Bye{}))
+
+	require.NoError(t, <-done)
+	assert.Equal(t, 2, c.games)
+	assert.Equal(t, 1, ia.resets)
+
+	// The second game starts from a fresh state, where we are werewolves
+	require.Len(t, ia.states, 2)
+	for _, state := range ia.states {
+		assert.Len(t, state.Grid, 3)
+		assert.Equal(t, model.Cell{Race: model.Ally, Count: 4}, state.Grid[home])
+		assert.Equal(t, model.Cell{Race: model.Enemy, Count: 4}, state.Grid[enemy])
+	}
+}
+
+func TestSessionByeDuringInit(t *testing.T) {
+	server, c, done := startPipeClient(t, &recordingIA{})
+	defer server.Close()
+
+	require.NoError(t, server.Send(protocol.Bye{}))
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, closed, c.session)
+}
+
+func TestSessionUnexpectedCommand(t *testing.T) {
+	server, _, done := startPipeClient(t, &recordingIA{})
+	defer server.Close()
+
+	require.NoError(t, server.Send(protocol.Upd{}))
+
+	err := <-done
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected command UPD while waiting for a new game")
+}
+
+func TestSessionResetsMinMaxIA(t *testing.T) {
+	ia := NewMinMaxIA(50 * time.Millisecond)
+	server, _, done := startPipeClient(t, ia)
+	defer server.Close()
+
+	require.NoError(t, server.Start(testGame(false)))
+	_, err := server.Play()
+	require.NoError(t, err)
+	first := ia.gameContext()
+	require.NoError(t, server.Send(protocol.End{}))
+
+	require.NoError(t, server.Start(testGame(true)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.Bye{}))
+	require.NoError(t, <-done)
+
+	// The searches of the first game are stopped, not the ones of the second
+	assert.Error(t, first.Err())
+	assert.NoError(t, ia.gameContext().Err())
+}
+
+func TestMinMaxIAReset(t *testing.T) {
+	ia := NewMinMaxIA(time.Minute)
+	state := model.GenerateComplicatedState()
+	search := func() chan model.Coup {
+		played := make(chan model.Coup, 1)
+		go func() { played <- ia.Play(state) }()
+		return played
+	}
+
+	// A search still running when the game ends is stopped
+	played := search()
+	time.Sleep(50 * time.Millisecond)
+	ia.Reset()
+	select {
+	case coup := <-played:
+		assert.NotEmpty(t, coup)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the search of the previous game is still running")
+	}
+
+	// The next game searches as usual
+	played = search()
+	select {
+	case <-played:
+		t.Fatal("the search of the new game was stopped")
+	case <-time.After(200 * time.Millisecond):
+	}
+	ia.Reset()
+	<-played
+}
@@ -2,12 +2,12 @@ package client
 
 import (
 	"fmt"
-	"log"
 	"net"
+	"time"
 
 	"github.com/langorou/langorou/pkg/client/model"
-
-	"github.com/langorou/langorou/pkg/utils"
+	"github.com/langorou/langorou/pkg/logging"
+	"github.com/langorou/langorou/pkg/protocol"
 )
 
 // A good ressource
@@ -34,228 +34,177 @@ const (
 // TCPClient handles the connection to the server, and also encapsulate the game
 type TCPClient struct {
 	conn         net.Conn
+	decoder      *protocol.Decoder
 	ourRaceCoord model.Coordinates
 	isWerewolf   bool // We assume we're a vampire
 	game         *Game
+	session      sessionState
+	games        int // number of games started on this connection
+	deadline     MoveDeadline
+	updReceived  time.Time
+	logger       *logging.Logger // fields of the player
+	log          *logging.Logger // fields of the player and of the current game
 }
 
-// NewTCPClient creates a new TCP client
+// NewTCPClient creates a new TCP client, TCP keepalive is enabled on the connection
 func NewTCPClient(addr string, name string, ia IA) (TCPClient, error) {
-
-	// We might need to use net.DialTCP with https://golang.org/pkg/net/#TCPConn.SetKeepAlive
-	conn, err := net.Dial("tcp", addr)
+	conn, err := dial(addr)
 	if err != nil {
 		return TCPClient{}, err
 	}
 
-	return TCPClient{
-		conn: conn,
-		game: NewGame(name, ia),
-	}, nil
+	return NewTCPClientWithConn(conn, name, ia), nil
 }
 
-// SendName to the server
-func (c *TCPClient) SendName() error {
-	name := c.game.Nme()
-	isASCII := utils.IsASCII(name)
-	if !isASCII {
-		return fmt.Errorf("%s is not a valid ASCII name", name)
+// NewTCPClientWithConn creates a new client over an existing connection
+func NewTCPClientWithConn(conn net.Conn, name string, ia IA) TCPClient {
+	c := TCPClient{
+		conn:    conn,
+		decoder: protocol.NewDecoder(conn),
+		game:    NewGame(name, ia),
 	}
+	c.SetLogger(logging.Default())
+	return c
+}
 
-	t := len(name)
-	if t == 0 || t > 255 {
-		return fmt.Errorf("invalid name '%s', please use a short ASCII name", name)
-	}
+// SetLogger sets the logger of the client, of the game and of the IA, the player, game and turn fields are added
+func (c *TCPClient) SetLogger(l *logging.Logger) {
+	c.logger = l.With("player", c.game.playerName)
+	c.log = c.logger.With("game", c.games)
+	c.game.SetLogger(c.log)
+}
 
-	msg := make([]byte, 3+1+t)
-	copy(msg[:3], "NME")
-	msg[3] = uint8(t)
-	copy(msg[4:], name)
+// SetMetrics enables the metrics of the player, nil disables them
+func (c *TCPClient) SetMetrics(m *PlayerMetrics) {
+	c.game.SetMetrics(m)
+}
 
-	_, err := c.conn.Write(msg)
+// SetMoveDeadline enables the watchdog sending a coup before the deadline even if the IA is still playing
+func (c *TCPClient) SetMoveDeadline(d MoveDeadline) {
+	c.deadline = d
+}
+
+// Close closes the connection to the server
+func (c *TCPClient) Close() error {
+	return c.conn.Close()
+}
 
-	return err
+// SendName to the server
+func (c *TCPClient) SendName() error {
+	return protocol.Encode(c.conn, protocol.Nme{Name: c.game.Nme()})
 }
 
 // SendMove to the server
 func (c *TCPClient) SendMove(moves []model.Move) error {
-
-	msg := make([]byte, 3+1+5*len(moves))
-
-	copy(msg[:3], "MOV")
-	msg[3] = uint8(len(moves))
-
-	log.Printf("===")
-	log.Printf("Player: %s sending %d moves:", c.game.playerName, len(moves))
-	for i, move := range moves {
-		log.Printf("Move: %+v", move)
-		msg[4+5*i] = move.Start.X
-		msg[4+5*i+1] = move.Start.Y
-		msg[4+5*i+2] = move.N
-		msg[4+5*i+3] = move.End.X
-		msg[4+5*i+4] = move.End.Y
-	}
-	log.Printf("===")
-
-	_, err := c.conn.Write(msg)
-
-	return err
+	c.log.Debug("sending moves", "turn", c.game.turn, "moves", moves)
+	return protocol.Encode(c.conn, protocol.Mov{Moves: moves})
 }
 
 // ReceiveMsg from the server and parse it
 func (c *TCPClient) ReceiveMsg() (ServerCmd, error) {
-	buf := make([]byte, 5)                          // we read at max 5 consecutive bytes
-	if _, err := c.conn.Read(buf[:3]); err != nil { // Read len(buf) chars, here 3 bytes
+	msg, err := c.decoder.Decode()
+	if err != nil {
 		return UNKNOWN, err
 	}
 
-	command := string(buf[:3])
-	log.Printf("Received command: %s", command)
-	switch command {
-	case "SET":
-		if _, err := c.conn.Read(buf[:2]); err != nil {
-			return SET, err
-		}
-		n := buf[0]
-		m := buf[1]
-
-		log.Printf("%s: set the map size to (%d, %d)", command, n, m)
+	command := ServerCmd(msg.Command())
+	c.log.Debug("received command", "command", command)
 
-		c.game.Set(n, m)
-
-		return SET, nil
-
-	case "HUM":
-		if _, err := c.conn.Read(buf[:1]); err != nil {
-			return HUM, err
-		}
-		n := buf[0]
-		coords := make([]model.Coordinates, n)
-		for i := 0; i < int(n); i++ {
-			if _, err := c.conn.Read(buf[:2]); err != nil {
-				return HUM, err
-			}
-			coords[i] = model.Coordinates{
-				X: buf[0],
-				Y: buf[1],
-			}
-		}
-		log.Printf("%s: Received %d positions of humans", command, len(coords))
-		c.game.Hum(coords)
-		return HUM, nil
-
-	case "HME":
-		if _, err := c.conn.Read(buf[:2]); err != nil {
-			return HME, err
-		}
-		x := buf[0]
-		y := buf[1]
-
-		c.ourRaceCoord = model.Coordinates{X: x, Y: y}
-		log.Printf("%s: Received our race coordinates: %+v", command, c.ourRaceCoord)
-
-		return HME, nil
-
-	case "UPD":
-		if _, err := c.conn.Read(buf[:1]); err != nil {
-			return UPD, err
-		}
-		n := buf[0]
-		changes := make([]model.Changes, n)
-		for i := 0; i < int(n); i++ {
-			if _, err := c.conn.Read(buf[:5]); err != nil {
-				return UPD, err
-			}
-
-			if c.isWerewolf {
-				changes[i] = model.Changes{
-					Coords: model.Coordinates{
-						X: buf[0],
-						Y: buf[1],
-					},
-					Neutral: buf[2],
-					Ally:    buf[4], // buf[4] represents the number of Werewolves
-					Enemy:   buf[3], // buf[3] represents the number of Vampires
-				}
-			} else {
-				changes[i] = model.Changes{
-					Coords: model.Coordinates{
-						X: buf[0],
-						Y: buf[1],
-					},
-					Neutral: buf[2],
-					Ally:    buf[3], // buf[3] represents the number of Vampires
-					Enemy:   buf[4], // buf[4] represents the number of Werewolves
-				}
-			}
+	next, err := c.session.next(command)
+	if err != nil {
+		return command, err
+	}
+	c.session = next
+
+	switch m := msg.(type) {
+	case protocol.Set:
+		c.games++
+		c.log = c.logger.With("game", c.games)
+		c.game.SetLogger(c.log)
+		c.log.Info("starting game", "rows", m.Rows, "columns", m.Columns)
+		c.game.Set(m.Rows, m.Columns)
+
+	case protocol.Hum:
+		c.log.Debug("received the positions of the humans", "humans", len(m.Coords))
+		c.game.Hum(m.Coords)
+
+	case protocol.Hme:
+		c.ourRaceCoord = m.Coords
+		c.log.Debug("received our start position", "x", c.ourRaceCoord.X, "y", c.ourRaceCoord.Y)
+
+	case protocol.Upd:
+		c.updReceived = time.Now()
+		changes, err := c.toChanges(m.Changes)
+		if err != nil {
+			return command, fmt.Errorf("%s: %s", command, err)
 		}
-		log.Printf("%s: received %d changes", command, n)
+		c.log.Debug("received changes", "command", command, "changes", len(changes))
 		c.game.Upd(changes)
-		return UPD, nil
-
-	case "MAP":
-		if _, err := c.conn.Read(buf[:1]); err != nil {
-			return MAP, err
-		}
-		n := buf[0]
-		changes := make([]model.Changes, n)
 
-		flip := false // If we see that our start position is one of werewolf, we need to flip ally and enemy
-		for i := 0; i < int(n); i++ {
-			if _, err := c.conn.Read(buf[:5]); err != nil {
-				return MAP, err
+	case protocol.Map:
+		// If we see that our start position is one of werewolf, we are werewolves
+		for _, change := range m.Changes {
+			if change.Coords == c.ourRaceCoord && change.Werewolves > 0 {
+				c.isWerewolf = true
 			}
-
-			changes[i] = model.Changes{
-				Coords: model.Coordinates{
-					X: buf[0],
-					Y: buf[1],
-				},
-				Neutral: buf[2],
-				Ally:    buf[3], // Vampire
-				Enemy:   buf[4], // Werewolf
-			}
-
-			// Set to true if and only if we're actually werewolves
-			flip = flip || (changes[i].Coords == c.ourRaceCoord && changes[i].Enemy > 0)
-
 		}
-		log.Printf("%s: received %d changes", command, n)
-
-		if flip {
-			log.Printf("%s: we are werewolves (%s)", command, c.game.playerName)
-			for i, c := range changes {
-				c.Ally, c.Enemy = c.Enemy, c.Ally
-				changes[i] = c
-			}
-
-			c.isWerewolf = true
-		} else {
-			log.Printf("%s: we are vampires (%s)", command, c.game.playerName)
+		race := "vampires"
+		if c.isWerewolf {
+			race = "werewolves"
 		}
+		c.log.Info("received the map", "race", race)
 
+		changes, err := c.toChanges(m.Changes)
+		if err != nil {
+			return command, fmt.Errorf("%s: %s", command, err)
+		}
+		c.log.Debug("received changes", "command", command, "changes", len(changes))
 		c.game.Map(changes)
 
-		return MAP, nil
-
-	case "END":
+	case protocol.End:
 		// Next Game
-		log.Printf("%s: end of the game", command)
+		c.log.Info("end of game", "turns", c.game.turn)
 		// we reset some variables
 		c.isWerewolf = false
 		c.ourRaceCoord = model.Coordinates{}
 
 		return END, c.game.End()
 
-	case "BYE":
+	case protocol.Bye:
 		// Server stop
-		log.Printf("%s: server said bye", command)
-		return BYE, nil
+		c.log.Info("server said bye", "games", c.games)
 
 	default:
-		return UNKNOWN, fmt.Errorf("invalid command from server : %s", command)
+		return UNKNOWN, fmt.Errorf("unexpected command from server: %s", command)
 	}
 
+	return command, nil
+}
+
+// toChanges converts the changes sent by the server to our point of view, it checks that they fit in the grid and
+// that each cell holds only one race
+func (c *TCPClient) toChanges(changes []protocol.Change) ([]model.Changes, error) {
+	res := make([]model.Changes, len(changes))
+	for i, change := range changes {
+		if change.Coords.X >= c.game.state.Width || change.Coords.Y >= c.game.state.Height {
+			return nil, fmt.Errorf("cell %+v is out of the %dx%d grid", change.Coords, c.game.state.Height, c.game.state.Width)
+		}
+		races := 0
+		for _, n := range []uint8{change.Humans, change.Vampires, change.Werewolves} {
+			if n > 0 {
+				races++
+			}
+		}
+		if races > 1 {
+			return nil, fmt.Errorf("impossible change, maximum one race per cell: %+v", change)
+		}
+
+		res[i] = model.Changes{Coords: change.Coords, Neutral: change.Humans, Ally: change.Vampires, Enemy: change.Werewolves}
+		if c.isWerewolf {
+			res[i].Ally, res[i].Enemy = change.Werewolves, change.Vampires
+		}
+	}
+	return res, nil
 }
 
 // ReceiveSpecificCommand returns an error if the command is not as expected
@@ -270,7 +219,7 @@ func (c *TCPClient) ReceiveSpecificCommand(assertCmd ServerCmd) error {
 	return nil
 }
 
-// Play the game, should be launched after Init()
+// Play the games of the session until the server says bye, should be launched after Init()
 func (c *TCPClient) Play() error {
 	for {
 		cmd, err := c.ReceiveMsg()
@@ -280,33 +229,37 @@ func (c *TCPClient) Play() error {
 
 		switch cmd {
 		case UPD:
-			if err = c.SendMove(c.game.Mov()); err != nil {
+			var moves []model.Move
+			if c.deadline.Deadline > 0 {
+				moves = c.game.MovBefore(c.updReceived, c.deadline.limit(c.updReceived))
+			} else {
+				moves = c.game.Mov()
+			}
+			if err = c.SendMove(moves); err != nil {
 				return err
 			}
 		case BYE:
-			log.Printf("Received BYE, stopping the client...")
 			return nil
-		case END:
-			log.Printf("Received END, ignoring it...")
-		default:
-			return fmt.Errorf("received unexpected command: %s", cmd)
 		}
+		// The other commands start a new game, they are handled by ReceiveMsg
 	}
 }
 
 // Start with the name
 func (c *TCPClient) Start() error {
-	// TODO: it's possible to receive BYE here, if we restarted a game
-
-	if err := c.Init(); err != nil {
+	err := c.Init()
+	if err == ErrServerBye {
+		return nil
+	}
+	if err != nil {
 		return fmt.Errorf("an error occurred during init: %s", err)
 	}
-	log.Print("Successfully init !")
 
 	return c.Play()
 }
 
-// Init the game
+// Init sends our name and receives the commands starting the first game, it returns ErrServerBye if the server
+// said bye instead
 func (c *TCPClient) Init() error {
 	// Send name
 	err := c.SendName()
@@ -314,28 +267,15 @@ func (c *TCPClient) Init() error {
 		return err
 	}
 
-	// Receive SET
-	err = c.ReceiveSpecificCommand(SET)
-	if err != nil {
-		return err
-	}
-
-	// Receive HUM
-	err = c.ReceiveSpecificCommand(HUM)
-	if err != nil {
-		return err
-	}
-
-	// Receive HME
-	err = c.ReceiveSpecificCommand(HME)
-	if err != nil {
-		return err
-	}
-
-	// Receive MAP
-	err = c.ReceiveSpecificCommand(MAP)
-	if err != nil {
-		return err
+	// Receive SET, HUM, HME and MAP
+	for c.session != playing {
+		cmd, err := c.ReceiveMsg()
+		if err != nil {
+			return err
+		}
+		if cmd == BYE {
+			return ErrServerBye
+		}
 	}
 
 	return nil
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
+	"github.com/langorou/langorou/pkg/mockserver"
+	"github.com/langorou/langorou/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingIA records the states it is asked to play and plays its coups in turn, it stays still once they are played
+type recordingIA struct {
+	coups  []model.Coup
+	states []*model.State
+	resets int
+}
+
+func (r *recordingIA) Play(state *model.State) model.Coup {
+	r.states = append(r.states, state)
+	if len(r.coups) == 0 {
+		return model.Coup{}
+	}
+	coup := r.coups[0]
+	r.coups = r.coups[1:]
+	return coup
+}
+
+func (r *recordingIA) Name() string {
+	return "recording"
+}
+
+func (r *recordingIA) Reset() {
+	r.resets++
+}
+
+var (
+	home  = model.Coordinates{X: 0, Y: 0}
+	human = model.Coordinates{X: 1, Y: 1}
+	enemy = model.Coordinates{X: 2, Y: 2}
+)
+
+// testGame is a 3x3 game with 4 vampires at (0, 0), 4 werewolves at (2, 2) and 2 humans at (1, 1)
+func testGame(werewolf bool) mockserver.Game {
+	g := mockserver.Game{
+		Rows:    3,
+		Columns: 3,
+		Home:    home,
+		Cells: []protocol.Change{
+			{Coords: home, Vampires: 4},
+			{Coords: human, Humans: 2},
+			{Coords: enemy, Werewolves: 4},
+		},
+	}
+	if werewolf {
+		g.Cells[0], g.Cells[2] = protocol.Change{Coords: home, Werewolves: 4}, protocol.Change{Coords: enemy, Vampires: 4}
+	}
+	return g
+}
+
+// startPipeClient connects a client to a mock server and runs it in the background, the error returned by Start is
+// sent on the channel
+func startPipeClient(t *testing.T, ia IA) (*mockserver.Server, *TCPClient, chan error) {
+	server, conn := mockserver.Pipe()
+	c := NewTCPClientWithConn(conn, "test", ia)
+
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+
+	name, err := server.ExpectName()
+	require.NoError(t, err)
+	assert.Equal(t, "test", name)
+
+	return server, &c, done
+}
+
+func TestTCPClientPerspectives(t *testing.T) {
+	for _, werewolf := range []bool{false, true} {
+		name := "vampires"
+		if werewolf {
+			name = "werewolves"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			coup := model.Coup{{Start: home, N: 4, End: human}}
+			ia := &recordingIA{coups: []model.Coup{coup}}
+			server, c, done := startPipeClient(t, ia)
+			defer server.Close()
+
+			require.NoError(t, server.Start(testGame(werewolf)))
+			moves, err := server.Play()
+			require.NoError(t, err)
+			assert.Equal(t, []model.Move(coup), moves)
+
+			// We took the humans, the enemy moves next to us
+			ally, foe := protocol.Change{Coords: human}, protocol.Change{Coords: model.Coordinates{X: 2, Y: 1}}
+			if werewolf {
+				ally.Werewolves, foe.Vampires = 6, 4
+			} else {
+				ally.Vampires, foe.Werewolves = 6, 4
+			}
+			_, err = server.Play(protocol.Change{Coords: home}, ally, protocol.Change{Coords: enemy}, foe)
+			require.NoError(t, err)
+
+			require.NoError(t, server.Send(protocol.Bye{}))
+			require.NoError(t, <-done)
+			assert.Equal(t, werewolf, c.isWerewolf)
+
+			require.Len(t, ia.states, 2)
+			first, second := ia.states[0], ia.states[1]
+			assert.Equal(t, model.Cell{Race: model.Ally, Count: 4}, first.Grid[home])
+			assert.Equal(t, model.Cell{Race: model.Neutral, Count: 2}, first.Grid[human])
+			assert.Equal(t, model.Cell{Race: model.Enemy, Count: 4}, first.Grid[enemy])
+
+			assert.Len(t, second.Grid, 2)
+			assert.Equal(t, model.Cell{Race: model.Ally, Count: 6}, second.Grid[human])
+			assert.Equal(t, model.Cell{Race: model.Enemy, Count: 4}, second.Grid[model.Coordinates{X: 2, Y: 1}])
+
+			assert.Len(t, server.Received(), 3) // NME and two MOV
+		})
+	}
+}
+
+func TestTCPClientLoopback(t *testing.T) {
+	server, err := mockserver.Listen()
+	require.NoError(t, err)
+	defer server.Close()
+
+	c, err := NewTCPClient(server.Addr(), "loopback", &recordingIA{})
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+
+	require.NoError(t, server.Accept())
+	name, err := server.ExpectName()
+	require.NoError(t, err)
+	assert.Equal(t, "loopback", name)
+
+	require.NoError(t, server.Start(testGame(false)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.Bye{}))
+	assert.NoError(t, <-done)
+}
+
+func TestTCPClientErrors(t *testing.T) {
+	t.Run("invalid command", func(t *testing.T) {
+		server, _, done := startPipeClient(t, &recordingIA{})
+		defer server.Close()
+
+		require.NoError(t, server.SendRaw([]byte("XYZ")))
+		err := <-done
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid command")
+	})
+
+	t.Run("truncated message", func(t *testing.T) {
+		server, _, done := startPipeClient(t, &recordingIA{})
+
+		require.NoError(t, server.Start(testGame(false)))
+		require.NoError(t, server.SendRaw([]byte("UPD\x02\x00\x00\x00\x04")))
+		require.NoError(t, server.Close())
+		err := <-done
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), io.ErrUnexpectedEOF.Error())
+	})
+
+	t.Run("connection closed", func(t *testing.T) {
+		server, _, done := startPipeClient(t, &recordingIA{})
+
+		require.NoError(t, server.Start(testGame(false)))
+		require.NoError(t, server.Close())
+		assert.Equal(t, io.EOF, <-done)
+	})
+
+	t.Run("two races on a cell", func(t *testing.T) {
+		server, _, done := startPipeClient(t, &recordingIA{})
+		defer server.Close()
+
+		require.NoError(t, server.Start(testGame(false)))
+		require.NoError(t, server.Send(protocol.Upd{Changes: []protocol.Change{{Coords: human, Humans: 2, Vampires: 3}}}))
+		err := <-done
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "maximum one race per cell")
+	})
+
+	t.Run("out of the grid", func(t *testing.T) {
+		server, _, done := startPipeClient(t, &recordingIA{})
+		defer server.Close()
+
+		require.NoError(t, server.Start(testGame(false)))
+		require.NoError(t, server.Send(protocol.Upd{Changes: []protocol.Change{{Coords: model.Coordinates{X: 3, Y: 0}, Humans: 2}}}))
+		err := <-done
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "out of the 3x3 grid")
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		server, conn := mockserver.Pipe()
+		defer server.Close()
+
+		c := NewTCPClientWithConn(conn, "", &recordingIA{})
+		assert.Error(t, c.Init())
+	})
+}
+
+func TestTCPClientLogs(t *testing.T) {
+	var b bytes.Buffer
+	server, conn := mockserver.Pipe()
+	defer server.Close()
+	c := NewTCPClientWithConn(conn, "test", NewMinMaxIA(10*time.Millisecond))
+	c.SetLogger(logging.New(&b, logging.DebugLevel, logging.JSONFormat).With("match", "m"))
+
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+	_, err := server.ExpectName()
+	require.NoError(t, err)
+	require.NoError(t, server.Start(testGame(false)))
+	_, err = server.Play()
+	require.NoError(t, err)
+	require.NoError(t, server.Send(protocol.Bye{}))
+	require.NoError(t, <-done)
+
+	entries := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		assert.Equal(t, "m", entry["match"])
+		assert.Equal(t, "test", entry["player"])
+		entries[entry["msg"].(string)] = entry
+	}
+
+	require.Contains(t, entries, "starting game")
+	assert.Equal(t, 1., entries["starting game"]["game"])
+	assert.Equal(t, "info", entries["starting game"]["level"])
+
+	// The search and the moves are logged with the turn
+	for _, msg := range []string{"search stopped", "sending moves"} {
+		require.Contains(t, entries, msg)
+		assert.Equal(t, 1., entries[msg]["game"], msg)
+		assert.Equal(t, 1., entries[msg]["turn"], msg)
+		assert.Equal(t, "debug", entries[msg]["level"], msg)
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
+)
+
+// DefaultMoveMargin is the default time kept before the move deadline to send the coup
+const DefaultMoveMargin = 150 * time.Millisecond
+
+// MoveDeadline configures the watchdog guaranteeing that a coup is sent in time, the server disqualifies late moves
+type MoveDeadline struct {
+	// Deadline is the time allowed by the server to play, measured from the reception of UPD, 0 disables the watchdog
+	Deadline time.Duration
+	// Margin is the time kept before the deadline to send the coup
+	Margin time.Duration
+}
+
+// limit returns the time before which the coup must be chosen
+func (d MoveDeadline) limit(received time.Time) time.Time {
+	return received.Add(d.Deadline - d.Margin)
+}
+
+// watchdog runs the IA in the background and falls back to the best coup known when the limit is reached: the best
+// coup so far for Anytime IAs, or a legal coup computed before starting the IA
+type watchdog struct {
+	// fallback computes the fallback coups, it is not shared with the IA which may still be running
+	fallback Heuristic
+
+	mu      sync.Mutex
+	running bool // true while the IA is still playing a previous turn
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{fallback: NewHeuristic(NewDefaultHeuristicParameters())}
+}
+
+// play returns the coup of the IA if it answers before limit, and the best coup known otherwise
+func (w *watchdog) play(ia IA, state *model.State, received time.Time, limit time.Time, log *logging.Logger) model.Coup {
+	best := w.fallback.randomMove(state)
+
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		// The IA can't play two turns at once, it still hangs on a previous one
+		log.Warn("IA still busy with a previous turn, sending the fallback coup")
+		return best
+	}
+	w.running = true
+	w.mu.Unlock()
+
+	var bestMu sync.Mutex
+	result := make(chan model.Coup, 1)
+	go func() {
+		var coup model.Coup
+		if a, ok := ia.(Anytime); ok {
+			coup = a.PlayAnytime(state, func(c model.Coup) {
+				bestMu.Lock()
+				best = c
+				bestMu.Unlock()
+			})
+		} else {
+			coup = ia.Play(state)
+		}
+
+		elapsed := time.Since(received)
+		if overrun := elapsed - limit.Sub(received); overrun > 0 {
+			log.Warn("IA answered after the limit", "elapsed", elapsed, "overrun", overrun)
+		}
+
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+		result <- coup
+	}()
+
+	timer := time.NewTimer(time.Until(limit))
+	defer timer.Stop()
+
+	select {
+	case coup := <-result:
+		return coup
+	case <-timer.C:
+		bestMu.Lock()
+		defer bestMu.Unlock()
+		log.Warn("IA still playing at the limit, sending the best coup known", "elapsed", time.Since(received))
+		return best
+	}
+}
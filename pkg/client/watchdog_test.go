@@ -0,0 +1,120 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
+	"github.com/langorou/langorou/pkg/mockserver"
+	"github.com/langorou/langorou/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingIA blocks until release is closed, after having reported its progress if it is not nil
+type hangingIA struct {
+	progress model.Coup
+	release  chan struct{}
+}
+
+func (h *hangingIA) Play(state *model.State) model.Coup {
+	<-h.release
+	return nil
+}
+
+func (h *hangingIA) PlayAnytime(state *model.State, progress func(coup model.Coup)) model.Coup {
+	if h.progress != nil {
+		progress(h.progress)
+	}
+	return h.Play(state)
+}
+
+func (h *hangingIA) Name() string {
+	return "hanging"
+}
+
+// blockingIA is a hangingIA which doesn't implement Anytime
+type blockingIA struct {
+	release chan struct{}
+}
+
+func (b *blockingIA) Play(state *model.State) model.Coup {
+	<-b.release
+	return nil
+}
+
+func (b *blockingIA) Name() string {
+	return "blocking"
+}
+
+func watchdogState() *model.State {
+	state := model.NewState(3, 3)
+	state.SetCell(home, model.Ally, 4)
+	state.SetCell(enemy, model.Enemy, 4)
+	return state
+}
+
+func TestWatchdog(t *testing.T) {
+	t.Run("in time", func(t *testing.T) {
+		coup := model.Coup{{Start: home, N: 4, End: human}}
+		start := time.Now()
+		played := newWatchdog().play(&recordingIA{coups: []model.Coup{coup}}, watchdogState(), start, start.Add(time.Second), logging.Discard())
+		assert.Equal(t, coup, played)
+	})
+
+	t.Run("best so far", func(t *testing.T) {
+		ia := &hangingIA{progress: model.Coup{{Start: home, N: 4, End: human}}, release: make(chan struct{})}
+		defer close(ia.release)
+
+		start := time.Now()
+		played := newWatchdog().play(ia, watchdogState(), start, start.Add(50*time.Millisecond), logging.Discard())
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+		assert.Equal(t, ia.progress, played)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		ia := &blockingIA{release: make(chan struct{})}
+		defer close(ia.release)
+
+		w := newWatchdog()
+		for i := 0; i < 2; i++ {
+			// The second turn starts while the IA still hangs on the first one
+			start := time.Now()
+			played := w.play(ia, watchdogState(), start, start.Add(50*time.Millisecond), logging.Discard())
+			assert.True(t, time.Since(start) < 500*time.Millisecond)
+
+			// The fallback is a legal coup moving our units
+			require.NotEmpty(t, played)
+			for _, move := range played {
+				assert.Equal(t, home, move.Start)
+				assert.Equal(t, 1., move.Start.Distance(move.End))
+			}
+		}
+	})
+}
+
+func TestTCPClientMoveDeadline(t *testing.T) {
+	ia := &blockingIA{release: make(chan struct{})}
+	defer close(ia.release)
+
+	server, conn := mockserver.Pipe()
+	defer server.Close()
+	c := NewTCPClientWithConn(conn, "test", ia)
+	c.SetMoveDeadline(MoveDeadline{Deadline: 200 * time.Millisecond, Margin: 100 * time.Millisecond})
+
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+	_, err := server.ExpectName()
+	require.NoError(t, err)
+
+	require.NoError(t, server.Start(testGame(false)))
+	start := time.Now()
+	moves, err := server.Play()
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) < 200*time.Millisecond, "moves received after %s", time.Since(start))
+	assert.NotEmpty(t, moves)
+
+	require.NoError(t, server.Send(protocol.Bye{}))
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,318 @@
+// Package config loads the configuration of the player from a YAML or JSON file and from environment variables, so
+// that tuning results don't require a recompile.
+//
+// The configuration starts from Default, then the keys of the file override it, then the environment variables: the
+// LANGOROU_ prefix followed by the path of the key in upper case, for instance LANGOROU_TIMEOUT or
+// LANGOROU_HEURISTIC_WIN_THRESHOLD. Unknown keys are rejected in both cases.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the configuration
+const EnvPrefix = "LANGOROU_"
+
+// IA types
+const (
+	MinMaxIA = "minmax"
+	DumbIA   = "dumb"
+	RemoteIA = "remote"
+)
+
+// Duration is a time.Duration written like "1600ms" in the configuration
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration %s, should be a string like \"1600ms\"", b)
+	}
+	return d.set(s)
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.set(s)
+}
+
+func (d *Duration) set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// Remote configures the remote IA
+type Remote struct {
+	// URL of the service
+	URL string `yaml:"url" json:"url"`
+	// Timeout before falling back to a local dumb IA
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+}
+
+// Heuristic mirrors client.HeuristicParameters with the keys of the configuration
+type Heuristic struct {
+	Counts           float64 `yaml:"counts" json:"counts"`
+	Battles          float64 `yaml:"battles" json:"battles"`
+	NeutralBattles   float64 `yaml:"neutral_battles" json:"neutral_battles"`
+	CumScore         float64 `yaml:"cum_score" json:"cum_score"`
+	WinScore         float64 `yaml:"win_score" json:"win_score"`
+	LoseOverWinRatio float64 `yaml:"lose_over_win_ratio" json:"lose_over_win_ratio"`
+	WinThreshold     float64 `yaml:"win_threshold" json:"win_threshold"`
+	MaxGroups        uint8   `yaml:"max_groups" json:"max_groups"`
+	Groups           float64 `yaml:"groups" json:"groups"`
+	Territory        float64 `yaml:"territory" json:"territory"`
+}
+
+// Params converts the heuristic to the parameters of the client
+func (h Heuristic) Params() client.HeuristicParameters {
+	return client.HeuristicParameters{
+		Counts:           h.Counts,
+		Battles:          h.Battles,
+		NeutralBattles:   h.NeutralBattles,
+		CumScore:         h.CumScore,
+		WinScore:         h.WinScore,
+		LoseOverWinRatio: h.LoseOverWinRatio,
+		WinThreshold:     h.WinThreshold,
+		MaxGroups:        h.MaxGroups,
+		Groups:           h.Groups,
+		Territory:        h.Territory,
+	}
+}
+
+// Endgame mirrors client.EndgameLimits, the positions solved exactly by the min max IA
+type Endgame struct {
+	// Groups is the maximum number of groups, humans included, 0 disables the solver
+	Groups int `yaml:"groups" json:"groups"`
+	// Units is the maximum number of units, humans included
+	Units int `yaml:"units" json:"units"`
+	// Plies is the maximum number of coups of both sides searched
+	Plies uint8 `yaml:"plies" json:"plies"`
+}
+
+// Limits converts the endgame to the limits of the client
+func (e Endgame) Limits() client.EndgameLimits {
+	return client.EndgameLimits{Groups: e.Groups, Units: e.Units, Plies: e.Plies}
+}
+
+// Config of the player
+type Config struct {
+	// Name of the player
+	Name string `yaml:"name" json:"name"`
+	// IA is the type of IA: minmax, dumb or remote
+	IA string `yaml:"ia" json:"ia"`
+	// Timeout is the time budget of the min max search
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+	// Deadline is the time allowed by the server to play, 0 disables the watchdog
+	Deadline Duration `yaml:"deadline" json:"deadline"`
+	// Margin is the time kept before the deadline to send the coup
+	Margin Duration `yaml:"margin" json:"margin"`
+	// Retries is the maximum number of consecutive reconnections, negative for no limit
+	Retries int `yaml:"retries" json:"retries"`
+	// Book is the path of the opening book of the min max IA, none if empty
+	Book      string    `yaml:"book" json:"book"`
+	Remote    Remote    `yaml:"remote" json:"remote"`
+	Heuristic Heuristic `yaml:"heuristic" json:"heuristic"`
+	Endgame   Endgame   `yaml:"endgame" json:"endgame"`
+}
+
+// Default returns the configuration which won the tournament
+func Default() Config {
+	return Config{
+		Name:     "langorou",
+		IA:       MinMaxIA,
+		Timeout:  Duration{1600 * time.Millisecond},
+		Deadline: Duration{2 * time.Second},
+		Margin:   Duration{client.DefaultMoveMargin},
+		Retries:  client.NewDefaultReconnect().MaxRetries,
+		Remote:   Remote{Timeout: Duration{1500 * time.Millisecond}},
+		Heuristic: Heuristic{
+			// Not risk averse at all
+			Counts:           1,
+			Battles:          0.02,
+			NeutralBattles:   0.03,
+			CumScore:         0.0001,
+			WinScore:         1e10,
+			LoseOverWinRatio: 0.8,
+			WinThreshold:     0.8,
+			MaxGroups:        2,
+			Groups:           0,
+		},
+		Endgame: Endgame{
+			Groups: client.DefaultEndgameGroups,
+			Units:  client.DefaultEndgameUnits,
+			Plies:  client.DefaultEndgamePlies,
+		},
+	}
+}
+
+// Load reads the file at path over the configuration, its format is given by its extension: .json, .yaml or .yml
+func (c *Config) Load(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, c)
+	default:
+		return fmt.Errorf("unknown format of %s, should be .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// fields returns the settable leaves of the configuration by environment variable name
+func (c *Config) fields() map[string]reflect.Value {
+	res := map[string]reflect.Value{}
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			name := prefix + strings.ToUpper(t.Field(i).Tag.Get("yaml"))
+			if f.Kind() == reflect.Struct && f.Type() != reflect.TypeOf(Duration{}) {
+				walk(f, name+"_")
+				continue
+			}
+			res[name] = f
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), EnvPrefix)
+	return res
+}
+
+// ApplyEnv overrides the configuration with the environment variables starting with EnvPrefix, environ is like
+// os.Environ()
+func (c *Config) ApplyEnv(environ []string) error {
+	fields := c.fields()
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, EnvPrefix) {
+			continue
+		}
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		name, value := kv[:i], kv[i+1:]
+
+		f, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("unknown configuration variable %s", name)
+		}
+		if err := set(f, value); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func set(f reflect.Value, value string) error {
+	if d, ok := f.Addr().Interface().(*Duration); ok {
+		return d.set(value)
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(v))
+	case reflect.Uint8:
+		v, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return err
+		}
+		f.SetUint(v)
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+// EnvNames returns the names of the environment variables overriding the configuration, sorted
+func (c Config) EnvNames() []string {
+	var names []string
+	for name := range c.fields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the consistency of the configuration
+func (c Config) Validate() error {
+	switch c.IA {
+	case MinMaxIA, DumbIA:
+	case RemoteIA:
+		if c.Remote.URL == "" {
+			return fmt.Errorf("the remote IA needs remote.url")
+		}
+	default:
+		return fmt.Errorf("unknown IA %q, should be %s, %s or %s", c.IA, MinMaxIA, DumbIA, RemoteIA)
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("timeout should be positive, got %s", c.Timeout)
+	}
+	if c.Deadline.Duration > 0 && c.Margin.Duration >= c.Deadline.Duration {
+		return fmt.Errorf("margin %s should be shorter than deadline %s", c.Margin, c.Deadline)
+	}
+	return nil
+}
+
+// String returns the configuration as YAML
+func (c Config) String() string {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
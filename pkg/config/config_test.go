@@ -0,0 +1,128 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"player.yaml": "timeout: 1s\nheuristic:\n  win_threshold: 0.9\n  max_groups: 3\nendgame:\n  plies: 8\n",
+		"player.json": `{"timeout": "1s", "heuristic": {"win_threshold": 0.9, "max_groups": 3}, "endgame": {"plies": 8}}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			c := Default()
+			require.NoError(t, c.Load(writeFile(t, dir, name, content)))
+
+			expected := Default()
+			expected.Timeout = Duration{time.Second}
+			expected.Heuristic.WinThreshold = 0.9
+			expected.Heuristic.MaxGroups = 3
+			expected.Endgame.Plies = 8
+			assert.Equal(t, expected, c)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"unknown.yaml":  "timeout: 1s\nheuristic:\n  win_treshold: 0.9\n",
+		"unknown.json":  `{"heuristic": {"win_treshold": 0.9}}`,
+		"duration.yaml": "timeout: 1600\n",
+		"duration.json": `{"timeout": 1600}`,
+		"player.toml":   "timeout = \"1s\"",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			c := Default()
+			assert.Error(t, c.Load(writeFile(t, dir, name, content)))
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	c := Default()
+	require.NoError(t, c.ApplyEnv([]string{
+		"HOME=/root",
+		"LANGOROU_IA=remote",
+		"LANGOROU_REMOTE_URL=http://localhost:8000",
+		"LANGOROU_MARGIN=200ms",
+		"LANGOROU_RETRIES=-1",
+		"LANGOROU_HEURISTIC_NEUTRAL_BATTLES=0.05",
+		"LANGOROU_HEURISTIC_MAX_GROUPS=4",
+	}))
+	assert.Equal(t, RemoteIA, c.IA)
+	assert.Equal(t, "http://localhost:8000", c.Remote.URL)
+	assert.Equal(t, 200*time.Millisecond, c.Margin.Duration)
+	assert.Equal(t, -1, c.Retries)
+	assert.Equal(t, 0.05, c.Heuristic.NeutralBattles)
+	assert.Equal(t, uint8(4), c.Heuristic.MaxGroups)
+	assert.NoError(t, c.Validate())
+
+	for _, env := range []string{"LANGOROU_TIMOUT=1s", "LANGOROU_TIMEOUT=1600", "LANGOROU_HEURISTIC_MAX_GROUPS=300"} {
+		c := Default()
+		assert.Error(t, c.ApplyEnv([]string{env}), env)
+	}
+
+	assert.Contains(t, Default().EnvNames(), "LANGOROU_HEURISTIC_LOSE_OVER_WIN_RATIO")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Default().Validate())
+
+	invalid := []func(c *Config){
+		func(c *Config) { c.IA = "alphazero" },
+		func(c *Config) { c.IA = RemoteIA },
+		func(c *Config) { c.Timeout = Duration{} },
+		func(c *Config) { c.Margin = c.Deadline },
+	}
+	for i, f := range invalid {
+		c := Default()
+		f(&c)
+		assert.Error(t, c.Validate(), i)
+	}
+}
+
+func TestString(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// The effective configuration can be loaded back
+	c := Default()
+	c.Timeout = Duration{time.Second}
+	path := writeFile(t, dir, "effective.yaml", c.String())
+
+	loaded := Config{}
+	require.NoError(t, loaded.Load(path))
+	assert.Equal(t, c, loaded)
+
+	var raw map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(c.String()), &raw))
+	assert.Equal(t, "1s", raw["timeout"])
+}
@@ -0,0 +1,123 @@
+package learn
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+// Sample is a position of a played match with its outcome for the Ally race: 1 for a win, 0 for a loss and 0.5 for
+// a tie
+type Sample struct {
+	Features []float64
+	Outcome  float64
+}
+
+// outcome returns the outcome of the match for a player
+func outcome(mr *tournament.MatchSummary, persp tournament.Perspective) float64 {
+	own, other := mr.Player1Eff, mr.Player2Eff
+	if persp == tournament.Player2 {
+		own, other = other, own
+	}
+	switch {
+	case own > other:
+		return 1
+	case own < other:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+// Samples returns every position of a match seen by both players, with the final outcome of the match for each
+func Samples(mr *tournament.MatchSummary) []Sample {
+	samples := make([]Sample, 0, 2*len(mr.History))
+	for _, persp := range []tournament.Perspective{tournament.Player1, tournament.Player2} {
+		o := outcome(mr, persp)
+		for _, p := range mr.History {
+			samples = append(samples, Sample{Features(tournament.StateFromPacked(p, persp)), o})
+		}
+	}
+	return samples
+}
+
+// DatasetWriter writes samples as CSV, with a header naming the features and a last outcome column
+type DatasetWriter struct {
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+// NewDatasetWriter creates a writer of samples to w, Flush should be called once done
+func NewDatasetWriter(w io.Writer) *DatasetWriter {
+	return &DatasetWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes a sample
+func (dw *DatasetWriter) Write(s Sample) error {
+	if len(s.Features) != len(FeatureNames) {
+		return fmt.Errorf("sample has %d features instead of %d", len(s.Features), len(FeatureNames))
+	}
+	if !dw.header {
+		dw.header = true
+		if err := dw.w.Write(append(append([]string{}, FeatureNames...), "outcome")); err != nil {
+			return err
+		}
+	}
+
+	dw.record = dw.record[:0]
+	for _, f := range s.Features {
+		dw.record = append(dw.record, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	dw.record = append(dw.record, strconv.FormatFloat(s.Outcome, 'g', -1, 64))
+	return dw.w.Write(dw.record)
+}
+
+// Flush writes the buffered samples
+func (dw *DatasetWriter) Flush() error {
+	dw.w.Flush()
+	return dw.w.Error()
+}
+
+// ReadDataset reads the samples written by a DatasetWriter, its features should be the current ones
+func ReadDataset(r io.Reader) ([]Sample, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(FeatureNames) + 1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := append(append([]string{}, FeatureNames...), "outcome")
+	for i, name := range columns {
+		if header[i] != name {
+			return nil, fmt.Errorf("column %d is %s instead of %s, the dataset was written with other features", i+1, header[i], name)
+		}
+	}
+
+	var samples []Sample
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]float64, len(record))
+		for i, field := range record {
+			if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("sample %d: invalid value %q for %s", len(samples)+1, field, columns[i])
+			}
+		}
+		samples = append(samples, Sample{Features: values[:len(FeatureNames)], Outcome: values[len(FeatureNames)]})
+	}
+}
@@ -0,0 +1,56 @@
+package learn
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/langorou/langorou/pkg/tournament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamples(t *testing.T) {
+	mr, err := tournament.LoadMatchSummary("../tournament/testdata/thetrap.json")
+	require.NoError(t, err)
+
+	samples := Samples(mr)
+	require.Len(t, samples, 2*len(mr.History))
+
+	// The players see the same positions with opposite outcomes
+	n := len(mr.History)
+	for i := 0; i < n; i++ {
+		assert.Equal(t, 1-samples[i].Outcome, samples[n+i].Outcome)
+		for j := range FeatureNames {
+			assert.InDelta(t, -samples[i].Features[j], samples[n+i].Features[j], 1e-9)
+		}
+	}
+}
+
+func TestDataset(t *testing.T) {
+	samples := []Sample{
+		{Features: []float64{1, 0.5, 0, -0.25, 1.5, 2, 0}, Outcome: 1},
+		{Features: []float64{-3, -0.1, 1, 0, 0, -1, 0.75}, Outcome: 0.5},
+	}
+
+	var buf bytes.Buffer
+	dw := NewDatasetWriter(&buf)
+	for _, s := range samples {
+		require.NoError(t, dw.Write(s))
+	}
+	require.NoError(t, dw.Flush())
+	assert.True(t, strings.HasPrefix(buf.String(), strings.Join(FeatureNames, ",")+",outcome\n"))
+
+	read, err := ReadDataset(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, samples, read)
+
+	assert.Error(t, dw.Write(Sample{Features: []float64{1}}))
+
+	_, err = ReadDataset(strings.NewReader("counts,groups\n1,2\n"))
+	assert.Error(t, err)
+
+	header := strings.Join(FeatureNames, ",") + ",outcome\n"
+	_, err = ReadDataset(strings.NewReader(header + "1,2,3,4,5,6,x,1\n"))
+	assert.EqualError(t, err, `sample 1: invalid value "x" for threats`)
+}
@@ -0,0 +1,90 @@
+// Package learn fits the weights of a linear evaluation of the states on the outcome of played matches: the features
+// of the states are extracted from the replays into a dataset, a logistic regression is trained on it and the
+// weights are loaded by the "linear" IA, a min max scoring its states with them.
+package learn
+
+import (
+	"math"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// FeatureNames are the names of the features, in the order of Features. Each feature is computed for both races and
+// is the value of the Ally race minus the one of the Enemy race, so that swapping the races negates it
+var FeatureNames = []string{
+	// counts is the number of units
+	"counts",
+	// count_ratio is the share of the units, in [-1, 1]
+	"count_ratio",
+	// groups is the number of groups
+	"groups",
+	// largest_group_ratio is the share of the units of a race in its largest group
+	"largest_group_ratio",
+	// neutral_opportunities sums the humans of the groups a group can surely convert, divided by their distance
+	"neutral_opportunities",
+	// territory is the number of humans a race reaches first with enough units to convert them
+	"territory",
+	// threats sums the units of the groups an opponent group can surely kill, divided by their distance, the more
+	// threats a race makes the better
+	"threats",
+}
+
+// surelyWins tells if attacker wins for sure against defender, humans are converted by as many units and monsters
+// are killed by 1.5 times as many units
+func surelyWins(attacker, defender model.Cell) bool {
+	if defender.Race == model.Neutral {
+		return attacker.Count >= defender.Count
+	}
+	return float64(attacker.Count) >= 1.5*float64(defender.Count)
+}
+
+// Features extracts the features of a state from the point of view of the Ally race
+func Features(s *model.State) []float64 {
+	var counts, groups, largest, opportunities, threats [3]float64
+
+	for c1, cell1 := range s.Grid {
+		if cell1.Race == model.Neutral {
+			continue
+		}
+		r := cell1.Race
+		counts[r] += float64(cell1.Count)
+		groups[r]++
+		largest[r] = math.Max(largest[r], float64(cell1.Count))
+
+		for c2, cell2 := range s.Grid {
+			if cell2.Race == r || !surelyWins(cell1, cell2) {
+				continue
+			}
+			switch cell2.Race {
+			case model.Neutral:
+				opportunities[r] += float64(cell2.Count) / c1.Distance(c2)
+			default:
+				threats[r] += float64(cell2.Count) / c1.Distance(c2)
+			}
+		}
+	}
+
+	ally, enemy := model.Ally, model.Enemy
+	var countRatio float64
+	if total := counts[ally] + counts[enemy]; total > 0 {
+		countRatio = (counts[ally] - counts[enemy]) / total
+	}
+	largestRatio := func(r model.Race) float64 {
+		if counts[r] == 0 {
+			return 0
+		}
+		return largest[r] / counts[r]
+	}
+	territoryAlly, territoryEnemy := client.Territory(s)
+
+	return []float64{
+		counts[ally] - counts[enemy],
+		countRatio,
+		groups[ally] - groups[enemy],
+		largestRatio(ally) - largestRatio(enemy),
+		opportunities[ally] - opportunities[enemy],
+		territoryAlly - territoryEnemy,
+		threats[ally] - threats[enemy],
+	}
+}
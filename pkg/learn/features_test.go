@@ -0,0 +1,54 @@
+package learn
+
+import (
+	"testing"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// swapRaces returns the state seen by the other race
+func swapRaces(s *model.State) *model.State {
+	swapped := model.NewState(s.Height, s.Width)
+	for coord, cell := range s.Grid {
+		race := cell.Race
+		if race != model.Neutral {
+			race = race.Opponent()
+		}
+		swapped.SetCell(coord, race, cell.Count)
+	}
+	return swapped
+}
+
+func TestFeatures(t *testing.T) {
+	s := model.NewState(5, 5)
+	s.SetCell(model.Coordinates{X: 0, Y: 0}, model.Ally, 6)
+	s.SetCell(model.Coordinates{X: 0, Y: 1}, model.Ally, 2)
+	s.SetCell(model.Coordinates{X: 4, Y: 4}, model.Enemy, 4)
+	s.SetCell(model.Coordinates{X: 2, Y: 2}, model.Neutral, 3)
+	s.SetCell(model.Coordinates{X: 4, Y: 0}, model.Neutral, 5)
+
+	features := Features(s)
+	require.Len(t, features, len(FeatureNames))
+	byName := map[string]float64{}
+	for i, name := range FeatureNames {
+		byName[name] = features[i]
+	}
+
+	assert.Equal(t, 8.-4, byName["counts"])
+	assert.Equal(t, (8.-4)/12, byName["count_ratio"])
+	assert.Equal(t, 2.-1, byName["groups"])
+	assert.Equal(t, 6./8-1, byName["largest_group_ratio"])
+	// The 6 reach the 3 humans in 2 moves and the 5 in 4, the 4 reach the 3 in 2 moves
+	assert.InDelta(t, 3./2+5./4-3./2, byName["neutral_opportunities"], 1e-9)
+	// The 3 humans are shared, the 5 go to the allies
+	assert.Equal(t, 3./2+5-3./2, byName["territory"])
+	// The 6 surely kill the 4 in 4 moves, and the 4 the 2 in 4 moves
+	assert.Equal(t, 4./4-2./4, byName["threats"])
+
+	// The features are antisymmetric
+	for i, f := range Features(swapRaces(s)) {
+		assert.InDelta(t, -features[i], f, 1e-9, FeatureNames[i])
+	}
+}
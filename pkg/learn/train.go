@@ -0,0 +1,135 @@
+package learn
+
+import (
+	"fmt"
+	"math"
+)
+
+// TrainOptions configures the logistic regression
+type TrainOptions struct {
+	// Epochs is the number of gradient descent steps over the whole dataset
+	Epochs int
+	// LearningRate is the size of the steps
+	LearningRate float64
+	// L2 is the coefficient of the regularization of the weights
+	L2 float64
+}
+
+// DefaultTrainOptions returns options converging on the datasets of a few tournaments
+func DefaultTrainOptions() TrainOptions {
+	return TrainOptions{Epochs: 500, LearningRate: 0.5, L2: 1e-3}
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Predict returns the probability of winning given by the weights for features
+func (w Weights) Predict(features []float64) float64 {
+	score := w.Bias
+	for i, name := range FeatureNames {
+		score += w.Weights[name] * features[i]
+	}
+	return sigmoid(score)
+}
+
+// LogLoss returns the mean cross entropy of the predictions of the weights on samples
+func LogLoss(w Weights, samples []Sample) float64 {
+	const eps = 1e-12
+	var loss float64
+	for _, s := range samples {
+		p := math.Min(math.Max(w.Predict(s.Features), eps), 1-eps)
+		loss -= s.Outcome*math.Log(p) + (1-s.Outcome)*math.Log(1-p)
+	}
+	return loss / float64(len(samples))
+}
+
+// Accuracy returns the share of the decided samples whose winner is predicted by the weights, ties are ignored
+func Accuracy(w Weights, samples []Sample) float64 {
+	var right, decided int
+	for _, s := range samples {
+		if s.Outcome == 0.5 {
+			continue
+		}
+		decided++
+		if (w.Predict(s.Features) > 0.5) == (s.Outcome > 0.5) {
+			right++
+		}
+	}
+	if decided == 0 {
+		return 0
+	}
+	return float64(right) / float64(decided)
+}
+
+// Train fits the weights of a logistic regression predicting the outcomes of the samples. The features are
+// standardized during the descent, the weights are given for the raw features
+func Train(samples []Sample, opts TrainOptions) (Weights, error) {
+	if len(samples) == 0 {
+		return Weights{}, fmt.Errorf("no sample to train on")
+	}
+	if opts.Epochs <= 0 || opts.LearningRate <= 0 || opts.L2 < 0 {
+		return Weights{}, fmt.Errorf("invalid options %+v", opts)
+	}
+
+	n, k := float64(len(samples)), len(FeatureNames)
+
+	mean, std := make([]float64, k), make([]float64, k)
+	for _, s := range samples {
+		for i, f := range s.Features {
+			mean[i] += f / n
+		}
+	}
+	for _, s := range samples {
+		for i, f := range s.Features {
+			std[i] += (f - mean[i]) * (f - mean[i]) / n
+		}
+	}
+	for i := range std {
+		std[i] = math.Sqrt(std[i])
+		// A constant feature can't be learned, it keeps a weight of 0. Rounding errors leave a tiny deviation
+		if std[i] < 1e-9 {
+			std[i] = math.Inf(1)
+		}
+	}
+
+	x := make([][]float64, len(samples))
+	for j, s := range samples {
+		x[j] = make([]float64, k)
+		for i, f := range s.Features {
+			x[j][i] = (f - mean[i]) / std[i]
+		}
+	}
+
+	weights, grad := make([]float64, k), make([]float64, k)
+	var bias float64
+	for epoch := 0; epoch < opts.Epochs; epoch++ {
+		for i := range grad {
+			grad[i] = opts.L2 * weights[i]
+		}
+		var gradBias float64
+		for j, s := range samples {
+			score := bias
+			for i, f := range x[j] {
+				score += weights[i] * f
+			}
+			diff := (sigmoid(score) - s.Outcome) / n
+			for i, f := range x[j] {
+				grad[i] += diff * f
+			}
+			gradBias += diff
+		}
+		for i := range weights {
+			weights[i] -= opts.LearningRate * grad[i]
+		}
+		bias -= opts.LearningRate * gradBias
+	}
+
+	// Undo the standardization: w.(f-mean)/std + b = (w/std).f + b - w.mean/std
+	w := Weights{Bias: bias, Weights: map[string]float64{}}
+	for i, name := range FeatureNames {
+		w.Weights[name] = weights[i] / std[i]
+		w.Bias -= weights[i] * mean[i] / std[i]
+	}
+	return w, nil
+}
@@ -0,0 +1,86 @@
+package learn
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syntheticSamples are won when counts + 0.5 territory > 0, with some noise
+func syntheticSamples(n int) []Sample {
+	r := rand.New(rand.NewSource(1))
+	samples := make([]Sample, n)
+	for i := range samples {
+		features := make([]float64, len(FeatureNames))
+		for j := range features {
+			features[j] = r.NormFloat64() * 10
+		}
+		// A constant feature
+		features[2] = 1
+		outcome := 0.
+		if features[0]+0.5*features[5]+r.NormFloat64() > 0 {
+			outcome = 1
+		}
+		samples[i] = Sample{features, outcome}
+	}
+	return samples
+}
+
+func TestTrain(t *testing.T) {
+	samples := syntheticSamples(2000)
+
+	w, err := Train(samples, DefaultTrainOptions())
+	require.NoError(t, err)
+
+	assert.Greater(t, w.Weights["counts"], 0.)
+	assert.InDelta(t, 0.5, w.Weights["territory"]/w.Weights["counts"], 0.1)
+	assert.Equal(t, 0., w.Weights["groups"])
+	assert.Less(t, LogLoss(w, samples), LogLoss(Weights{}, samples))
+	assert.Greater(t, Accuracy(w, samples), 0.9)
+
+	_, err = Train(nil, DefaultTrainOptions())
+	assert.Error(t, err)
+	_, err = Train(samples, TrainOptions{})
+	assert.Error(t, err)
+}
+
+func TestWeights(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "weights.json")
+	w := Weights{Name: "test", Bias: 0.1, Weights: map[string]float64{"counts": 0.5, "territory": 0.2}}
+	require.NoError(t, w.Save(path))
+	loaded, err := LoadWeights(path)
+	require.NoError(t, err)
+	assert.Equal(t, w, loaded)
+
+	s := model.GenerateSimpleState()
+	l, err := NewLinear(w)
+	require.NoError(t, err)
+	features := Features(s)
+	assert.InDelta(t, 0.1+0.5*features[0]+0.2*features[5], l.Evaluate(s), 1e-9)
+
+	_, err = NewLinear(Weights{Weights: map[string]float64{"luck": 1}})
+	assert.EqualError(t, err, "unknown features luck, should be among counts, count_ratio, groups, largest_group_ratio, neutral_opportunities, territory, threats")
+
+	// The linear IA searches with the weights
+	ia, err := client.NewIA("linear:timeout=100ms,weights=" + path)
+	require.NoError(t, err)
+	require.IsType(t, &client.MinMaxIA{}, ia)
+	h := ia.(*client.MinMaxIA).Heuristic()
+	assert.Equal(t, l, h.Evaluator())
+	assert.Contains(t, ia.Name(), "_test")
+	assert.NotEmpty(t, ia.Play(s))
+
+	_, err = client.NewIA("linear")
+	assert.EqualError(t, err, "IA linear: missing weights")
+}
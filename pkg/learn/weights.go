@@ -0,0 +1,145 @@
+package learn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// Weights of a linear evaluation, the score of a state is Bias plus the sum of its features times their weights.
+// Trained by a logistic regression, it is the log odds of winning
+type Weights struct {
+	// Name identifies the weights in the name of the IA
+	Name    string             `json:"name"`
+	Bias    float64            `json:"bias"`
+	Weights map[string]float64 `json:"weights"`
+}
+
+// Validate checks that the weights are for known features, the missing ones weigh 0
+func (w Weights) Validate() error {
+	var unknown []string
+	for name := range w.Weights {
+		if featureIndex(name) < 0 {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown features %s, should be among %s", strings.Join(unknown, ", "), strings.Join(FeatureNames, ", "))
+	}
+	return nil
+}
+
+func featureIndex(name string) int {
+	for i, n := range FeatureNames {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// LoadWeights reads weights saved as JSON
+func LoadWeights(path string) (Weights, error) {
+	var w Weights
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return w, err
+	}
+	if err = json.Unmarshal(b, &w); err != nil {
+		return w, fmt.Errorf("invalid weights %s: %s", path, err)
+	}
+	if err = w.Validate(); err != nil {
+		return w, fmt.Errorf("invalid weights %s: %s", path, err)
+	}
+	return w, nil
+}
+
+// Save writes the weights as JSON
+func (w Weights) Save(path string) error {
+	b, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Linear is an evaluator scoring the states with weights
+type Linear struct {
+	name    string
+	bias    float64
+	weights []float64
+}
+
+var _ client.Evaluator = &Linear{}
+
+// NewLinear creates an evaluator from weights
+func NewLinear(w Weights) (*Linear, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	l := &Linear{name: w.Name, bias: w.Bias, weights: make([]float64, len(FeatureNames))}
+	for name, weight := range w.Weights {
+		l.weights[featureIndex(name)] = weight
+	}
+	if l.name == "" {
+		l.name = "linear"
+	}
+	return l, nil
+}
+
+// Evaluate implements client.Evaluator
+func (l *Linear) Evaluate(s *model.State) float64 {
+	score := l.bias
+	for i, f := range Features(s) {
+		score += l.weights[i] * f
+	}
+	return score
+}
+
+// Name implements client.Evaluator
+func (l *Linear) Name() string {
+	return l.name
+}
+
+func init() {
+	client.Register(
+		"linear",
+		"min max scoring the states with the weights trained by langorou train, options: weights (path, required), timeout (1s), book (path of an opening book), the limits of the endgame solver endgame_groups, endgame_units and endgame_plies, and the heuristic parameters of the search max_groups, cum_score, win_score and lose_over_win_ratio",
+		func(opts *client.Options) (client.IA, error) {
+			path := opts.String("weights", "")
+			if path == "" {
+				return nil, fmt.Errorf("missing weights")
+			}
+			timeout := opts.Duration("timeout", client.DefaultMinMaxTimeout)
+			if timeout <= 0 {
+				return nil, fmt.Errorf("timeout should be positive, got %s", timeout)
+			}
+			params := client.NewDefaultHeuristicParameters()
+			params.MaxGroups = opts.Uint8("max_groups", params.MaxGroups)
+			params.CumScore = opts.Float("cum_score", params.CumScore)
+			params.WinScore = opts.Float("win_score", params.WinScore)
+			params.LoseOverWinRatio = opts.Float("lose_over_win_ratio", params.LoseOverWinRatio)
+
+			w, err := LoadWeights(path)
+			if err != nil {
+				return nil, err
+			}
+			l, err := NewLinear(w)
+			if err != nil {
+				return nil, err
+			}
+			ia := client.NewMinMaxIAWithEvaluator(timeout, params, l)
+			ia.SetEndgameLimits(client.EndgameLimitsFromOptions(opts, client.NewDefaultEndgameLimits()))
+			if err = client.SetBookFromOptions(ia, opts); err != nil {
+				return nil, err
+			}
+			return ia, nil
+		},
+	)
+}
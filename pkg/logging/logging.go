@@ -0,0 +1,229 @@
+// Package logging implements a small leveled and structured logger. Each entry has a level, a message and fields
+// given as key value pairs, it is written on one line as text (key=value) or as a JSON object.
+//
+// Loggers derived with With share the output and the level of their parent and add fields to all their entries,
+// like the player, the game and the turn.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level of an entry, entries below the level of the logger are dropped
+type Level int8
+
+// Levels from the most to the least verbose
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return fmt.Sprintf("level(%d)", l)
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("invalid log level %q, should be one of %s", s, strings.Join(levelNames, ", "))
+}
+
+// Format of the entries
+type Format int8
+
+const (
+	// TextFormat writes time=... level=... msg=... key=value lines
+	TextFormat Format = iota
+	// JSONFormat writes one JSON object per line
+	JSONFormat
+)
+
+// ParseFormat parses text or json
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return TextFormat, nil
+	case "json":
+		return JSONFormat, nil
+	}
+	return TextFormat, fmt.Errorf("invalid log format %q, should be text or json", s)
+}
+
+// Parse creates a logger from the names of a level and of a format, as given on the command line
+func Parse(w io.Writer, level string, format string) (*Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return New(w, l, f), nil
+}
+
+// output is shared by a logger and the loggers derived from it
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format Format
+	now    func() time.Time
+}
+
+// Logger writes entries with its fields, it is safe for concurrent use
+type Logger struct {
+	out    *output
+	fields []interface{} // key value pairs
+}
+
+// New creates a logger writing the entries of at least level to w
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{out: &output{w: w, level: level, format: format, now: time.Now}}
+}
+
+// Discard returns a logger dropping all the entries
+func Discard() *Logger {
+	return New(ioutil.Discard, ErrorLevel+1, TextFormat)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stderr, InfoLevel, TextFormat)
+)
+
+// Default returns the logger used when none is given, it writes text entries of at least info level to stderr
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the default logger, the loggers already derived from the previous one are not affected
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// With returns a logger adding the key value pairs to all its entries
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(append(fields, l.fields...), keyvals...)
+	return &Logger{out: l.out, fields: fields}
+}
+
+// Enabled returns true if the entries of level are written, it avoids building costly fields for nothing
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+// Debug writes an entry with the debug level, keyvals are key value pairs added to the fields of the logger
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(DebugLevel, msg, keyvals)
+}
+
+// Info writes an entry with the info level
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(InfoLevel, msg, keyvals)
+}
+
+// Warn writes an entry with the warn level
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(WarnLevel, msg, keyvals)
+}
+
+// Error writes an entry with the error level
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(ErrorLevel, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := make([]interface{}, 0, 6+len(l.fields)+len(keyvals))
+	entry = append(entry, "time", l.out.now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg)
+	entry = append(append(entry, l.fields...), keyvals...)
+	if len(entry)%2 != 0 {
+		entry = append(entry, "MISSING")
+	}
+
+	var b bytes.Buffer
+	if l.out.format == JSONFormat {
+		writeJSON(&b, entry)
+	} else {
+		writeText(&b, entry)
+	}
+	b.WriteByte('\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(b.Bytes())
+}
+
+// value converts the values which don't have a useful JSON representation to strings
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+func writeJSON(b *bytes.Buffer, entry []interface{}) {
+	b.WriteByte('{')
+	for i := 0; i < len(entry); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(entry[i]))
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(value(entry[i+1]))
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprintf("%+v", entry[i+1]))
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+}
+
+func writeText(b *bytes.Buffer, entry []interface{}) {
+	for i := 0; i < len(entry); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(fmt.Sprint(entry[i]))
+		b.WriteByte('=')
+
+		s := fmt.Sprintf("%+v", value(entry[i+1]))
+		if s == "" || strings.ContainsAny(s, " =\"\n\t") {
+			s = strconv.Quote(s)
+		}
+		b.WriteString(s)
+	}
+}
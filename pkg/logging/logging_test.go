@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(level Level, format Format) (*Logger, *bytes.Buffer) {
+	var b bytes.Buffer
+	l := New(&b, level, format)
+	l.out.now = func() time.Time { return time.Date(2020, 3, 30, 12, 0, 0, 0, time.UTC) }
+	return l, &b
+}
+
+func TestText(t *testing.T) {
+	l, b := newTestLogger(InfoLevel, TextFormat)
+	l = l.With("player", "langorou", "game", 2)
+
+	l.Debug("dropped")
+	l.Info("sending moves", "turn", 3, "moves", 1, "took", 150*time.Millisecond)
+	l.With("turn", 4).Error("failed", "err", errors.New("connection reset"), "odd")
+
+	assert.Equal(t, `time=2020-03-30T12:00:00Z level=info msg="sending moves" player=langorou game=2 turn=3 moves=1 took=150ms
+time=2020-03-30T12:00:00Z level=error msg=failed player=langorou game=2 turn=4 err="connection reset" odd=MISSING
+`, b.String())
+}
+
+func TestJSON(t *testing.T) {
+	l, b := newTestLogger(DebugLevel, JSONFormat)
+	l.With("player", "langorou").Debug("received", "command", "UPD", "changes", []int{1, 2}, "err", errors.New("boom"))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, map[string]interface{}{
+		"time":    "2020-03-30T12:00:00Z",
+		"level":   "debug",
+		"msg":     "received",
+		"player":  "langorou",
+		"command": "UPD",
+		"changes": []interface{}{1., 2.},
+		"err":     "boom",
+	}, entry)
+}
+
+func TestLevels(t *testing.T) {
+	for _, name := range []string{"debug", "INFO", "Warn", "error"} {
+		level, err := ParseLevel(name)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToLower(name), level.String(), name)
+	}
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+
+	l, b := newTestLogger(WarnLevel, TextFormat)
+	assert.False(t, l.Enabled(InfoLevel))
+	assert.True(t, l.With("k", "v").Enabled(ErrorLevel))
+	l.Info("dropped")
+	assert.Empty(t, b.String())
+
+	assert.False(t, Discard().Enabled(ErrorLevel))
+}
@@ -0,0 +1,274 @@
+// Package metrics implements counters, gauges and histograms exposed over HTTP in the Prometheus text exposition
+// format, so that the behaviour of the engine can be followed during a game.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type of a metric family
+type Type string
+
+// Types of the text exposition format
+const (
+	CounterType   Type = "counter"
+	GaugeType     Type = "gauge"
+	HistogramType Type = "histogram"
+)
+
+// series is a metric with a given set of labels
+type series interface {
+	// write writes the samples of the series
+	write(w io.Writer, name string, labels string)
+}
+
+// family groups the series sharing a name
+type family struct {
+	name   string
+	help   string
+	typ    Type
+	series map[string]series // by formatted labels
+}
+
+// Registry holds the metrics, it is safe for concurrent use
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// labelEscaper escapes a label value as the text exposition format expects, unlike strconv.Quote it keeps the other
+// characters as they are
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// formatLabels formats label pairs as {k1="v1",k2="v2"}, labels are name value pairs
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metrics: odd number of label names and values %v", labels))
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+quoteLabel(labels[i+1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// register returns the series of name with labels, creating it with create if needed. It panics if name is already
+// used by a metric of another type
+func (r *Registry) register(name, help string, typ Type, labels []string, create func() series) series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ, series: map[string]series{}}
+		r.families[name] = f
+	}
+	if f.typ != typ {
+		panic(fmt.Sprintf("metrics: %s is already registered as a %s", name, f.typ))
+	}
+
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter returns the counter name with the given label name value pairs, it is created at the first call
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return r.register(name, help, CounterType, labels, func() series { return &Counter{} }).(*Counter)
+}
+
+// Gauge returns the gauge name with the given label name value pairs, it is created at the first call
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return r.register(name, help, GaugeType, labels, func() series { return &Gauge{} }).(*Gauge)
+}
+
+// Histogram returns the histogram name with the given label name value pairs, it is created at the first call with
+// the upper bounds of its buckets, +Inf is implicit
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return r.register(name, help, HistogramType, labels, func() series { return newHistogram(buckets) }).(*Histogram)
+}
+
+// WriteText writes all the metrics in the text exposition format, sorted by name and labels
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.name, f.typ)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f.series[key].write(bw, f.name, key)
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics in the text exposition format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WriteText(w)
+	})
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a value which only goes up
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Inc adds 1 to the counter
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds v to the counter, it panics if v is negative
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: a counter can't decrease")
+	}
+	c.mu.Lock()
+	c.v += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+func (c *Counter) write(w io.Writer, name string, labels string) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatValue(c.Value()))
+}
+
+// Gauge is a value which goes up and down
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Set sets the value of the gauge
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.v = v
+	g.mu.Unlock()
+}
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+func (g *Gauge) write(w io.Writer, name string, labels string) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatValue(g.Value()))
+}
+
+// Histogram counts the observations in buckets
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []uint64 // not cumulative, the last one is +Inf
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	bounds = append([]float64{}, bounds...)
+	sort.Float64s(bounds)
+	return &Histogram{bounds: bounds, buckets: make([]uint64, len(bounds)+1)}
+}
+
+// Observe adds an observation
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v) // first bound >= v
+	h.mu.Lock()
+	h.buckets[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// withLabel adds a label to formatted labels
+func withLabel(labels string, name, value string) string {
+	pair := name + "=" + quoteLabel(value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func (h *Histogram) write(w io.Writer, name string, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cumulative uint64
+	for i, n := range h.buckets {
+		cumulative += n
+		bound := math.Inf(1)
+		if i < len(h.bounds) {
+			bound = h.bounds[i]
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatValue(bound)), cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatValue(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("moves_total", "Coups sent.").Inc()
+	r.Counter("moves_total", "Coups sent.").Add(2)
+	r.Gauge("population", "Units.", "race", "enemy").Set(8)
+	r.Gauge("population", "Units.", "race", "ally").Set(12.5)
+	h := r.Histogram("depth", "Depth reached.", []float64{4, 2})
+	for _, v := range []float64{1, 2, 3, 7} {
+		h.Observe(v)
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, r.WriteText(&b))
+	assert.Equal(t, `# HELP depth Depth reached.
+# TYPE depth histogram
+depth_bucket{le="2"} 2
+depth_bucket{le="4"} 3
+depth_bucket{le="+Inf"} 4
+depth_sum 13
+depth_count 4
+# HELP moves_total Coups sent.
+# TYPE moves_total counter
+moves_total 3
+# HELP population Units.
+# TYPE population gauge
+population{race="ally"} 12.5
+population{race="enemy"} 8
+`, b.String())
+}
+
+func TestHistogramLabels(t *testing.T) {
+	r := NewRegistry()
+	r.Histogram("duration_seconds", "Time.", []float64{0.5}, "player", "p1").Observe(0.25)
+
+	var b bytes.Buffer
+	require.NoError(t, r.WriteText(&b))
+	assert.Contains(t, b.String(), `duration_seconds_bucket{player="p1",le="0.5"} 1`)
+	assert.Contains(t, b.String(), `duration_seconds_sum{player="p1"} 0.25`)
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("games_total", "Games.", "player", "é\tbob \"the\" \\ wolf\n").Inc()
+	r.Histogram("duration_seconds", "Time.", []float64{0.5}, "player", "é").Observe(0.25)
+
+	var b bytes.Buffer
+	require.NoError(t, r.WriteText(&b))
+	assert.Contains(t, b.String(), "games_total{player=\"é\tbob \\\"the\\\" \\\\ wolf\\n\"} 1")
+	assert.Contains(t, b.String(), `duration_seconds_bucket{player="é",le="0.5"} 1`)
+}
+
+func TestRegistryErrors(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("moves_total", "Coups sent.")
+	assert.Panics(t, func() { r.Gauge("moves_total", "Coups sent.") })
+	assert.Panics(t, func() { r.Counter("moves_total", "Coups sent.").Add(-1) })
+	assert.Panics(t, func() { r.Gauge("population", "Units.", "race") })
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("moves_total", "Coups sent.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "moves_total 1\n")
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+}
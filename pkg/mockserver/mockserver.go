@@ -0,0 +1,191 @@
+// Package mockserver implements a scriptable game server to test the players without the twilight server.
+//
+// The test drives the server: it sends the messages of its choice, including malformed ones, and reads the replies of
+// the player, which are all recorded.
+package mockserver
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/protocol"
+)
+
+// DefaultTimeout is the maximum time to wait for a reply of the player
+const DefaultTimeout = 5 * time.Second
+
+// Server is a fake game server handling a single player
+type Server struct {
+	listener net.Listener // nil for a server over a pipe
+	conn     net.Conn
+	decoder  *protocol.Decoder
+
+	// Timeout is the maximum time to wait for a reply of the player
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	received []protocol.Message
+}
+
+// Listen creates a server listening on a loopback port, see Addr and Accept
+func Listen() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return &Server{listener: l, Timeout: DefaultTimeout}, nil
+}
+
+// Addr returns the address on which the server listens
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Accept waits for the player to connect
+func (s *Server) Accept() error {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return err
+	}
+	s.setConn(conn)
+	return nil
+}
+
+// Pipe creates a server connected to the returned connection through an in-memory pipe
+func Pipe() (*Server, net.Conn) {
+	client, conn := net.Pipe()
+	s := &Server{Timeout: DefaultTimeout}
+	s.setConn(conn)
+	return s, client
+}
+
+func (s *Server) setConn(conn net.Conn) {
+	s.conn = conn
+	s.decoder = protocol.NewDecoder(conn)
+	s.decoder.SetTimeouts(0, 0) // Receive sets its own deadline
+}
+
+// Close closes the connection to the player and stops listening
+func (s *Server) Close() error {
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+	}
+	if s.listener != nil {
+		if lerr := s.listener.Close(); err == nil {
+			err = lerr
+		}
+	}
+	return err
+}
+
+// Disconnect closes the connection to the player but keeps listening, the player may connect again with Accept
+func (s *Server) Disconnect() error {
+	return s.conn.Close()
+}
+
+// Send sends messages to the player
+func (s *Server) Send(msgs ...protocol.Message) error {
+	for _, msg := range msgs {
+		if err := protocol.Encode(s.conn, msg); err != nil {
+			return fmt.Errorf("sending %s: %s", msg.Command(), err)
+		}
+	}
+	return nil
+}
+
+// SendRaw sends bytes as they are, to send malformed messages or to split a message
+func (s *Server) SendRaw(b []byte) error {
+	_, err := s.conn.Write(b)
+	return err
+}
+
+// Receive waits for the next message of the player and records it
+func (s *Server) Receive() (protocol.Message, error) {
+	if err := s.conn.SetReadDeadline(time.Now().Add(s.Timeout)); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.decoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.received = append(s.received, msg)
+	s.mu.Unlock()
+	return msg, nil
+}
+
+// Expect waits for the next message of the player and checks its command
+func (s *Server) Expect(cmd protocol.Command) (protocol.Message, error) {
+	msg, err := s.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if msg.Command() != cmd {
+		return msg, fmt.Errorf("expected %s but received %s", cmd, msg.Command())
+	}
+	return msg, nil
+}
+
+// ExpectName waits for the name of the player
+func (s *Server) ExpectName() (string, error) {
+	msg, err := s.Expect(protocol.NME)
+	if err != nil {
+		return "", err
+	}
+	return msg.(protocol.Nme).Name, nil
+}
+
+// ExpectMoves waits for the moves of the player
+func (s *Server) ExpectMoves() ([]model.Move, error) {
+	msg, err := s.Expect(protocol.MOV)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(protocol.Mov).Moves, nil
+}
+
+// Received returns the messages received from the player so far
+func (s *Server) Received() []protocol.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]protocol.Message{}, s.received...)
+}
+
+// Game describes the initial position of a game
+type Game struct {
+	Rows, Columns uint8
+	// Home is the starting position of the player
+	Home  model.Coordinates
+	Cells []protocol.Change
+}
+
+// Start sends the messages starting the game: SET, HUM, HME and MAP
+func (s *Server) Start(g Game) error {
+	var humans []model.Coordinates
+	for _, c := range g.Cells {
+		if c.Humans > 0 {
+			humans = append(humans, c.Coords)
+		}
+	}
+
+	return s.Send(
+		protocol.Set{Rows: g.Rows, Columns: g.Columns},
+		protocol.Hum{Coords: humans},
+		protocol.Hme{Coords: g.Home},
+		protocol.Map{Changes: g.Cells},
+	)
+}
+
+// Play sends an update and waits for the moves of the player
+func (s *Server) Play(changes ...protocol.Change) ([]model.Move, error) {
+	if err := s.Send(protocol.Upd{Changes: changes}); err != nil {
+		return nil, err
+	}
+	return s.ExpectMoves()
+}
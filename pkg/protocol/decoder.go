@@ -0,0 +1,194 @@
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+const (
+	// DefaultIdleTimeout is the maximum time to wait for the next message, it is long since a player waits for the
+	// other player before receiving UPD
+	DefaultIdleTimeout = 10 * time.Minute
+	// DefaultFrameTimeout is the maximum time to receive the rest of a message once its command was received
+	DefaultFrameTimeout = 10 * time.Second
+)
+
+// deadliner is implemented by net.Conn
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// Decoder reads the messages of both directions from a stream, it doesn't depend on how the bytes are split by the network
+type Decoder struct {
+	r        *bufio.Reader
+	deadline deadliner // nil if the reader doesn't support deadlines
+
+	idleTimeout  time.Duration
+	frameTimeout time.Duration
+}
+
+// NewDecoder creates a decoder with the default timeouts, deadlines are only set if r supports them (like net.Conn)
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{
+		r:            bufio.NewReader(r),
+		idleTimeout:  DefaultIdleTimeout,
+		frameTimeout: DefaultFrameTimeout,
+	}
+	if dl, ok := r.(deadliner); ok {
+		d.deadline = dl
+	}
+	return d
+}
+
+// SetTimeouts sets the maximum time to wait for the next message and to receive the rest of a message once its
+// command was received, 0 disables a timeout
+func (d *Decoder) SetTimeouts(idle, frame time.Duration) {
+	d.idleTimeout = idle
+	d.frameTimeout = frame
+}
+
+// setDeadline sets the read deadline if the reader supports it, errors are ignored: a closed connection may refuse
+// deadlines while messages are still buffered, and the reads report the failures anyway
+func (d *Decoder) setDeadline(timeout time.Duration) {
+	if d.deadline == nil {
+		return
+	}
+	var t time.Time
+	if timeout > 0 {
+		t = time.Now().Add(timeout)
+	}
+	d.deadline.SetReadDeadline(t)
+}
+
+// readFull reads exactly len(buf) bytes, io.EOF in the middle of a message is reported as io.ErrUnexpectedEOF
+func (d *Decoder) readFull(cmd Command, buf []byte) error {
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("%s: %s", cmd, err)
+	}
+	return nil
+}
+
+func (d *Decoder) readCoords(cmd Command, buf []byte) (model.Coordinates, error) {
+	if err := d.readFull(cmd, buf[:2]); err != nil {
+		return model.Coordinates{}, err
+	}
+	return model.Coordinates{X: buf[0], Y: buf[1]}, nil
+}
+
+func (d *Decoder) readChanges(cmd Command, buf []byte) ([]Change, error) {
+	if err := d.readFull(cmd, buf[:1]); err != nil {
+		return nil, err
+	}
+	changes := make([]Change, buf[0])
+	for i := range changes {
+		if err := d.readFull(cmd, buf[:5]); err != nil {
+			return nil, err
+		}
+		changes[i] = Change{
+			Coords:     model.Coordinates{X: buf[0], Y: buf[1]},
+			Humans:     buf[2],
+			Vampires:   buf[3],
+			Werewolves: buf[4],
+		}
+	}
+	return changes, nil
+}
+
+// Decode reads the next message, io.EOF is returned if the stream ended between two messages
+func (d *Decoder) Decode() (Message, error) {
+	d.setDeadline(d.idleTimeout)
+
+	buf := make([]byte, maxItems) // we read at max 255 consecutive bytes (a name)
+	if _, err := io.ReadFull(d.r, buf[:3]); err != nil {
+		return nil, err
+	}
+	cmd := Command(buf[:3])
+
+	// The rest of the message is expected right away
+	d.setDeadline(d.frameTimeout)
+
+	switch cmd {
+	case NME:
+		if err := d.readFull(cmd, buf[:1]); err != nil {
+			return nil, err
+		}
+		n := buf[0]
+		if err := d.readFull(cmd, buf[:n]); err != nil {
+			return nil, err
+		}
+		return Nme{Name: string(buf[:n])}, nil
+
+	case MOV:
+		if err := d.readFull(cmd, buf[:1]); err != nil {
+			return nil, err
+		}
+		moves := make([]model.Move, buf[0])
+		for i := range moves {
+			if err := d.readFull(cmd, buf[:5]); err != nil {
+				return nil, err
+			}
+			moves[i] = model.Move{
+				Start: model.Coordinates{X: buf[0], Y: buf[1]},
+				N:     buf[2],
+				End:   model.Coordinates{X: buf[3], Y: buf[4]},
+			}
+		}
+		return Mov{Moves: moves}, nil
+
+	case SET:
+		if err := d.readFull(cmd, buf[:2]); err != nil {
+			return nil, err
+		}
+		return Set{Rows: buf[0], Columns: buf[1]}, nil
+
+	case HUM:
+		if err := d.readFull(cmd, buf[:1]); err != nil {
+			return nil, err
+		}
+		coords := make([]model.Coordinates, buf[0])
+		for i := range coords {
+			c, err := d.readCoords(cmd, buf)
+			if err != nil {
+				return nil, err
+			}
+			coords[i] = c
+		}
+		return Hum{Coords: coords}, nil
+
+	case HME:
+		c, err := d.readCoords(cmd, buf)
+		if err != nil {
+			return nil, err
+		}
+		return Hme{Coords: c}, nil
+
+	case MAP:
+		changes, err := d.readChanges(cmd, buf)
+		if err != nil {
+			return nil, err
+		}
+		return Map{Changes: changes}, nil
+
+	case UPD:
+		changes, err := d.readChanges(cmd, buf)
+		if err != nil {
+			return nil, err
+		}
+		return Upd{Changes: changes}, nil
+
+	case END:
+		return End{}, nil
+
+	case BYE:
+		return Bye{}, nil
+	}
+
+	return nil, fmt.Errorf("invalid command: %q", string(cmd))
+}
@@ -0,0 +1,197 @@
+// Package protocol implements the wire protocol of the game between the server and the players.
+//
+// Every message starts with a 3 letters command followed by its payload, all numbers are single bytes:
+//
+//	player -> server: NME <length> <name>, MOV <n> n*(<x> <y> <count> <x> <y>)
+//	server -> player: SET <rows> <columns>, HUM <n> n*(<x> <y>), HME <x> <y>, MAP and UPD <n> n*(<x> <y> <humans> <vampires> <werewolves>), END, BYE
+package protocol
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/utils"
+)
+
+// Command is the name of a message
+type Command string
+
+// Commands of the protocol
+const (
+	NME Command = "NME"
+	MOV Command = "MOV"
+	SET Command = "SET"
+	HUM Command = "HUM"
+	HME Command = "HME"
+	MAP Command = "MAP"
+	UPD Command = "UPD"
+	END Command = "END"
+	BYE Command = "BYE"
+)
+
+// Message is a message of the protocol
+type Message interface {
+	Command() Command
+}
+
+// Nme is sent by a player to give its name
+type Nme struct {
+	Name string
+}
+
+// Mov is sent by a player to play its moves
+type Mov struct {
+	Moves []model.Move
+}
+
+// Set gives the size of the map
+type Set struct {
+	Rows, Columns uint8
+}
+
+// Hum gives the positions of the humans
+type Hum struct {
+	Coords []model.Coordinates
+}
+
+// Hme gives the starting position of the player
+type Hme struct {
+	Coords model.Coordinates
+}
+
+// Change is the content of a cell, as sent by the server
+type Change struct {
+	Coords     model.Coordinates
+	Humans     uint8
+	Vampires   uint8
+	Werewolves uint8
+}
+
+// Map gives the initial content of the map
+type Map struct {
+	Changes []Change
+}
+
+// Upd gives the cells changed by the last moves, the player has to play after receiving it
+type Upd struct {
+	Changes []Change
+}
+
+// End tells that the game is over, a new one may start
+type End struct{}
+
+// Bye tells that the server stops
+type Bye struct{}
+
+// Command implements Message
+func (Nme) Command() Command { return NME }
+
+// Command implements Message
+func (Mov) Command() Command { return MOV }
+
+// Command implements Message
+func (Set) Command() Command { return SET }
+
+// Command implements Message
+func (Hum) Command() Command { return HUM }
+
+// Command implements Message
+func (Hme) Command() Command { return HME }
+
+// Command implements Message
+func (Map) Command() Command { return MAP }
+
+// Command implements Message
+func (Upd) Command() Command { return UPD }
+
+// Command implements Message
+func (End) Command() Command { return END }
+
+// Command implements Message
+func (Bye) Command() Command { return BYE }
+
+// maxItems is the maximum number of items of a list, their count is sent as a single byte
+const maxItems = 255
+
+func checkCount(cmd Command, n int) error {
+	if n > maxItems {
+		return fmt.Errorf("%s: too many items (%d), maximum is %d", cmd, n, maxItems)
+	}
+	return nil
+}
+
+// Marshal returns the encoding of a message
+func Marshal(msg Message) ([]byte, error) {
+	b := []byte(msg.Command())
+
+	switch m := msg.(type) {
+	case Nme:
+		if !utils.IsASCII(m.Name) {
+			return nil, fmt.Errorf("%s is not a valid ASCII name", m.Name)
+		}
+		if len(m.Name) == 0 || len(m.Name) > maxItems {
+			return nil, fmt.Errorf("invalid name '%s', please use a short ASCII name", m.Name)
+		}
+		b = append(b, uint8(len(m.Name)))
+		b = append(b, m.Name...)
+
+	case Mov:
+		if err := checkCount(MOV, len(m.Moves)); err != nil {
+			return nil, err
+		}
+		b = append(b, uint8(len(m.Moves)))
+		for _, move := range m.Moves {
+			b = append(b, move.Start.X, move.Start.Y, move.N, move.End.X, move.End.Y)
+		}
+
+	case Set:
+		b = append(b, m.Rows, m.Columns)
+
+	case Hum:
+		if err := checkCount(HUM, len(m.Coords)); err != nil {
+			return nil, err
+		}
+		b = append(b, uint8(len(m.Coords)))
+		for _, c := range m.Coords {
+			b = append(b, c.X, c.Y)
+		}
+
+	case Hme:
+		b = append(b, m.Coords.X, m.Coords.Y)
+
+	case Map:
+		return appendChanges(b, MAP, m.Changes)
+
+	case Upd:
+		return appendChanges(b, UPD, m.Changes)
+
+	case End, Bye:
+
+	default:
+		return nil, fmt.Errorf("unknown message %T", msg)
+	}
+
+	return b, nil
+}
+
+func appendChanges(b []byte, cmd Command, changes []Change) ([]byte, error) {
+	if err := checkCount(cmd, len(changes)); err != nil {
+		return nil, err
+	}
+	b = append(b, uint8(len(changes)))
+	for _, c := range changes {
+		b = append(b, c.Coords.X, c.Coords.Y, c.Humans, c.Vampires, c.Werewolves)
+	}
+	return b, nil
+}
+
+// Encode writes a message in a single write
+func Encode(w io.Writer, msg Message) error {
+	b, err := Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,227 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	c := model.Coordinates{X: 1, Y: 2}
+	cases := []struct {
+		msg      Message
+		expected []byte
+	}{
+		{Nme{Name: "langorou"}, []byte("NME\x08langorou")},
+		{Mov{Moves: []model.Move{{Start: c, N: 3, End: model.Coordinates{X: 2, Y: 3}}}}, []byte("MOV\x01\x01\x02\x03\x02\x03")},
+		{Set{Rows: 5, Columns: 10}, []byte("SET\x05\x0a")},
+		{Hum{Coords: []model.Coordinates{c, {X: 4, Y: 5}}}, []byte("HUM\x02\x01\x02\x04\x05")},
+		{Hme{Coords: c}, []byte("HME\x01\x02")},
+		{Map{Changes: []Change{{c, 0, 4, 0}}}, []byte("MAP\x01\x01\x02\x00\x04\x00")},
+		{Upd{Changes: []Change{{c, 0, 0, 7}, {c, 2, 0, 0}}}, []byte("UPD\x02\x01\x02\x00\x00\x07\x01\x02\x02\x00\x00")},
+		{Upd{}, []byte("UPD\x00")},
+		{End{}, []byte("END")},
+		{Bye{}, []byte("BYE")},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.msg.Command()), func(t *testing.T) {
+			b, err := Marshal(tc.msg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, b)
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := Marshal(Nme{Name: ""})
+	assert.Error(t, err)
+	_, err = Marshal(Nme{Name: "héhé"})
+	assert.Error(t, err)
+	_, err = Marshal(Nme{Name: strings.Repeat("a", 256)})
+	assert.Error(t, err)
+	_, err = Marshal(Hum{Coords: make([]model.Coordinates, 256)})
+	assert.Error(t, err)
+	_, err = Marshal(Mov{Moves: make([]model.Move, 256)})
+	assert.Error(t, err)
+}
+
+func randomCoords(r *rand.Rand) model.Coordinates {
+	return model.Coordinates{X: uint8(r.Intn(256)), Y: uint8(r.Intn(256))}
+}
+
+func randomChanges(r *rand.Rand) []Change {
+	changes := make([]Change, r.Intn(256))
+	for i := range changes {
+		changes[i] = Change{randomCoords(r), uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256))}
+	}
+	return changes
+}
+
+func randomMessage(r *rand.Rand) Message {
+	switch r.Intn(9) {
+	case 0:
+		name := make([]byte, 1+r.Intn(255))
+		for i := range name {
+			name[i] = byte(r.Intn(128))
+		}
+		return Nme{Name: string(name)}
+	case 1:
+		moves := make([]model.Move, r.Intn(256))
+		for i := range moves {
+			moves[i] = model.Move{Start: randomCoords(r), N: uint8(r.Intn(256)), End: randomCoords(r)}
+		}
+		return Mov{Moves: moves}
+	case 2:
+		return Set{Rows: uint8(r.Intn(256)), Columns: uint8(r.Intn(256))}
+	case 3:
+		coords := make([]model.Coordinates, r.Intn(256))
+		for i := range coords {
+			coords[i] = randomCoords(r)
+		}
+		return Hum{Coords: coords}
+	case 4:
+		return Hme{Coords: randomCoords(r)}
+	case 5:
+		return Map{Changes: randomChanges(r)}
+	case 6:
+		return Upd{Changes: randomChanges(r)}
+	case 7:
+		return End{}
+	default:
+		return Bye{}
+	}
+}
+
+// chunkReader returns the data in chunks of random sizes, like TCP segments can be
+type chunkReader struct {
+	r    *rand.Rand
+	data []byte
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if len(cr.data) == 0 {
+		return 0, io.EOF
+	}
+	n := 1 + cr.r.Intn(len(cr.data))
+	if n > len(p) {
+		n = len(p)
+	}
+	copy(p, cr.data[:n])
+	cr.data = cr.data[n:]
+	return n, nil
+}
+
+func decodeAll(t *testing.T, r io.Reader, expected int) []Message {
+	dec := NewDecoder(r)
+	var msgs []Message
+	for {
+		msg, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+		require.True(t, len(msgs) <= expected, "too many messages decoded")
+	}
+	return msgs
+}
+
+// TestDecodeChunked is a fuzz test: random streams of messages split at random positions must decode to the same
+// messages whatever the split
+func TestDecodeChunked(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 200; i++ {
+		var stream []byte
+		var expected []Message
+		for j := 0; j < 1+r.Intn(20); j++ {
+			msg := randomMessage(r)
+			b, err := Marshal(msg)
+			require.NoError(t, err)
+			expected = append(expected, msg)
+			stream = append(stream, b...)
+		}
+
+		readers := map[string]io.Reader{
+			"whole":    bytes.NewReader(stream),
+			"one byte": iotest.OneByteReader(bytes.NewReader(stream)),
+			"half":     iotest.HalfReader(bytes.NewReader(stream)),
+			"data err": iotest.DataErrReader(bytes.NewReader(stream)),
+			"chunks":   &chunkReader{r: r, data: stream},
+		}
+		for name, reader := range readers {
+			assert.Equal(t, expected, decodeAll(t, reader, len(expected)), "iteration %d, reader %s", i, name)
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+
+	for i := 0; i < 200; i++ {
+		msg := randomMessage(r)
+		data, err := Marshal(msg)
+		require.NoError(t, err)
+		if len(data) <= 3 {
+			continue // END and BYE can't be truncated after their command
+		}
+
+		cut := 3 + r.Intn(len(data)-3)
+		_, err = NewDecoder(&chunkReader{r: r, data: data[:cut]}).Decode()
+		require.Error(t, err, "%s cut at %d", msg.Command(), cut)
+		assert.Contains(t, err.Error(), io.ErrUnexpectedEOF.Error())
+	}
+}
+
+func TestDecodeInvalidCommand(t *testing.T) {
+	msg, err := NewDecoder(strings.NewReader("FOO")).Decode()
+	assert.Error(t, err)
+	assert.Nil(t, msg)
+}
+
+func TestDecodeDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	dec := NewDecoder(client)
+	dec.SetTimeouts(0, 50*time.Millisecond)
+
+	go func() {
+		// The server sends the beginning of an update and stalls
+		server.Write([]byte("UPD\x01\x02"))
+	}()
+
+	_, err := dec.Decode()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+}
+
+func TestEncodeDecode(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		Encode(server, Set{Rows: 3, Columns: 4})
+		Encode(server, Bye{})
+	}()
+
+	dec := NewDecoder(client)
+	msg, err := dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, Set{Rows: 3, Columns: 4}, msg)
+	msg, err = dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, Bye{}, msg)
+}
@@ -0,0 +1,58 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/logging"
+)
+
+// maxRequestSize is the maximum size of a state, a 255x255 grid full of cells takes less than 4MB
+const maxRequestSize = 4 << 20
+
+// NewHandler exposes an IA with the schema of the package. The requests are played one at a time since the IAs
+// aren't safe for concurrent use
+func NewHandler(ia client.IA) http.Handler {
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/name", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, NameResponse{Name: ia.Name()})
+	})
+
+	mux.HandleFunc("/play", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req State
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid state: %s", err), http.StatusBadRequest)
+			return
+		}
+		state, err := req.ToState()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid state: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		coup := ia.Play(state)
+		mu.Unlock()
+
+		writeJSON(w, FromCoup(coup))
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.Default().Error("writing the response", "err", err)
+	}
+}
@@ -0,0 +1,145 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/logging"
+)
+
+// IA forwards Play to a remote service. If the service doesn't answer a valid coup in time, the coup of a local
+// fallback IA is played instead
+type IA struct {
+	url     string
+	timeout time.Duration
+	http    *http.Client
+
+	mu       sync.Mutex // protects log and fallback, which may be set while playing
+	log      *logging.Logger
+	fallback client.IA
+}
+
+var _ client.IA = &IA{}
+var _ client.Logged = &IA{}
+var _ client.Resetter = &IA{}
+
+// DefaultTimeout is the time allowed to the service of a remote IA created from a spec without timeout
+const DefaultTimeout = 1500 * time.Millisecond
+
+func init() {
+	client.Register("remote", "an IA served over HTTP (see pkg/remote), options: url (required) and timeout (1.5s) before falling back to a dumb IA", func(opts *client.Options) (client.IA, error) {
+		url := opts.String("url", "")
+		if url == "" {
+			return nil, fmt.Errorf("missing url")
+		}
+		return NewIA(url, opts.Duration("timeout", DefaultTimeout)), nil
+	})
+}
+
+// NewIA creates an IA playing with the service at url (without the /play suffix), falling back to a DumbIA when the
+// service takes more than timeout to answer
+func NewIA(url string, timeout time.Duration) *IA {
+	return &IA{
+		url:      strings.TrimSuffix(url, "/"),
+		timeout:  timeout,
+		http:     &http.Client{},
+		fallback: client.NewDumbIA(),
+		log:      logging.Default(),
+	}
+}
+
+// SetLogger implements client.Logged
+func (r *IA) SetLogger(l *logging.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = l
+}
+
+func (r *IA) logger() *logging.Logger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.log
+}
+
+// SetFallback sets the IA playing when the service fails
+func (r *IA) SetFallback(ia client.IA) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = ia
+}
+
+func (r *IA) fallbackIA() client.IA {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fallback
+}
+
+// Reset implements client.Resetter, the fallback is reset since the service itself knows nothing of the games
+func (r *IA) Reset() {
+	if res, ok := r.fallbackIA().(client.Resetter); ok {
+		res.Reset()
+	}
+}
+
+// request sends the state to the service and returns its coup
+func (r *IA) request(state *model.State) (model.Coup, error) {
+	body, err := json.Marshal(FromState(state))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, r.url+"/play", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var coup Coup
+	if err := json.NewDecoder(resp.Body).Decode(&coup); err != nil {
+		return nil, fmt.Errorf("invalid coup: %s", err)
+	}
+	return coup.ToCoup(), nil
+}
+
+// Play asks the coup to the service, the coup is checked against the rules
+func (r *IA) Play(state *model.State) model.Coup {
+	start := time.Now()
+	coup, err := r.request(state)
+	if err == nil {
+		err = model.ValidateCoup(state, model.Ally, coup)
+	}
+	if err != nil {
+		fallback := r.fallbackIA()
+		r.logger().Warn("remote IA failed, falling back", "url", r.url, "elapsed", time.Since(start), "fallback", fallback.Name(), "err", err)
+		return fallback.Play(state)
+	}
+	return coup
+}
+
+// Name of the IA
+func (r *IA) Name() string {
+	return fmt.Sprintf("remote_%s", r.url)
+}
@@ -0,0 +1,173 @@
+package remote
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	home  = model.Coordinates{X: 0, Y: 0}
+	human = model.Coordinates{X: 1, Y: 1}
+	enemy = model.Coordinates{X: 2, Y: 2}
+)
+
+func testState() *model.State {
+	s := model.NewState(3, 4)
+	s.SetCell(home, model.Ally, 4)
+	s.SetCell(human, model.Neutral, 2)
+	s.SetCell(enemy, model.Enemy, 5)
+	return s
+}
+
+// fixedIA always plays the same coup
+type fixedIA struct {
+	coup   model.Coup
+	delay  time.Duration
+	resets int
+}
+
+func (f *fixedIA) Reset() {
+	f.resets++
+}
+
+func (f *fixedIA) Play(state *model.State) model.Coup {
+	time.Sleep(f.delay)
+	return f.coup
+}
+
+func (f *fixedIA) Name() string {
+	return "fixed"
+}
+
+func TestSchema(t *testing.T) {
+	s := FromState(testState())
+	assert.Equal(t, State{Height: 3, Width: 4, Cells: []Cell{
+		{X: 0, Y: 0, Race: RaceAlly, Count: 4},
+		{X: 1, Y: 1, Race: RaceNeutral, Count: 2},
+		{X: 2, Y: 2, Race: RaceEnemy, Count: 5},
+	}}, s)
+
+	state, err := s.ToState()
+	require.NoError(t, err)
+	assert.Equal(t, testState().Grid, state.Grid)
+	assert.Equal(t, uint8(3), state.Height)
+	assert.Equal(t, uint8(4), state.Width)
+
+	coup := model.Coup{{Start: home, N: 4, End: human}}
+	assert.Equal(t, coup, FromCoup(coup).ToCoup())
+
+	_, err = State{Height: 3, Width: 4, Cells: []Cell{{X: 4, Y: 0, Race: RaceAlly, Count: 1}}}.ToState()
+	assert.Error(t, err)
+	_, err = State{Height: 3, Width: 4, Cells: []Cell{{X: 0, Y: 0, Race: "orcs", Count: 1}}}.ToState()
+	assert.Error(t, err)
+	_, err = State{Height: 3, Width: 4, Cells: []Cell{{X: 0, Y: 0, Race: RaceAlly, Count: 0}}}.ToState()
+	assert.Error(t, err)
+	_, err = State{Height: 3, Width: 4, Cells: []Cell{
+		{X: 0, Y: 0, Race: RaceAlly, Count: 4},
+		{X: 0, Y: 0, Race: RaceEnemy, Count: 5},
+	}}.ToState()
+	assert.Error(t, err)
+}
+
+func TestRemoteIA(t *testing.T) {
+	coup := model.Coup{{Start: home, N: 4, End: human}}
+	fallback := &fixedIA{coup: model.Coup{{Start: home, N: 4, End: model.Coordinates{X: 1, Y: 0}}}}
+
+	t.Run("remote coup", func(t *testing.T) {
+		server := httptest.NewServer(NewHandler(&fixedIA{coup: coup}))
+		defer server.Close()
+
+		ia := NewIA(server.URL, time.Second)
+		ia.SetFallback(fallback)
+		assert.Equal(t, coup, ia.Play(testState()))
+	})
+
+	t.Run("slow service", func(t *testing.T) {
+		server := httptest.NewServer(NewHandler(&fixedIA{coup: coup, delay: 500 * time.Millisecond}))
+		defer server.Close()
+
+		ia := NewIA(server.URL, 50*time.Millisecond)
+		ia.SetFallback(fallback)
+		start := time.Now()
+		assert.Equal(t, fallback.coup, ia.Play(testState()))
+		assert.True(t, time.Since(start) < 400*time.Millisecond)
+	})
+
+	t.Run("invalid coup", func(t *testing.T) {
+		server := httptest.NewServer(NewHandler(&fixedIA{coup: model.Coup{{Start: enemy, N: 5, End: human}}}))
+		defer server.Close()
+
+		ia := NewIA(server.URL, time.Second)
+		ia.SetFallback(fallback)
+		assert.Equal(t, fallback.coup, ia.Play(testState()))
+	})
+
+	t.Run("no service", func(t *testing.T) {
+		ia := NewIA("http://127.0.0.1:1", time.Second)
+		ia.SetFallback(fallback)
+		assert.Equal(t, fallback.coup, ia.Play(testState()))
+	})
+
+	t.Run("fallback set while playing", func(t *testing.T) {
+		ia := NewIA("http://127.0.0.1:1", time.Second)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ia.SetFallback(fallback)
+		}()
+		ia.Play(testState())
+		<-done
+		assert.Equal(t, fallback.coup, ia.Play(testState()))
+	})
+}
+
+func TestRemoteIAReset(t *testing.T) {
+	fallback := &fixedIA{}
+	ia := NewIA("http://localhost:1", time.Second)
+	ia.SetFallback(fallback)
+	ia.Reset()
+	assert.Equal(t, 1, fallback.resets)
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(NewHandler(&fixedIA{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/name")
+	require.NoError(t, err)
+	var name NameResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&name))
+	resp.Body.Close()
+	assert.Equal(t, "fixed", name.Name)
+
+	resp, err = http.Get(server.URL + "/play")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	for _, body := range []string{"{", `{"height": 1, "width": 1, "cells": [{"x": 1, "y": 0, "race": "ally", "count": 1}]}`} {
+		resp, err = http.Post(server.URL+"/play", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	ia, err := client.NewIA("remote:url=http://localhost:8000,timeout=200ms")
+	require.NoError(t, err)
+	require.IsType(t, &IA{}, ia)
+	assert.Equal(t, 200*time.Millisecond, ia.(*IA).timeout)
+
+	_, err = client.NewIA("remote:timeout=200ms")
+	assert.EqualError(t, err, "IA remote: missing url")
+}
@@ -0,0 +1,137 @@
+// Package remote plays with IAs running in other processes: the state is sent as JSON over HTTP and the coup is
+// read back, so that evaluators can be prototyped with any tool.
+//
+// The service answers POST /play with the coup to play in a state, and GET /name with the name of its IA:
+//
+//	POST /play {"height": 5, "width": 10, "cells": [{"x": 4, "y": 1, "race": "ally", "count": 4}, ...]}
+//	=> {"moves": [{"start": {"x": 4, "y": 1}, "n": 4, "end": {"x": 3, "y": 2}}]}
+//
+//	GET /name => {"name": "min_max"}
+//
+// The races are given from the point of view of the player: "ally", "enemy" or "neutral" for the humans.
+package remote
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/langorou/langorou/pkg/client/model"
+)
+
+// Race names used in the schema
+const (
+	RaceNeutral = "neutral"
+	RaceAlly    = "ally"
+	RaceEnemy   = "enemy"
+)
+
+var raceNames = map[model.Race]string{model.Neutral: RaceNeutral, model.Ally: RaceAlly, model.Enemy: RaceEnemy}
+
+// Coordinates of a cell
+type Coordinates struct {
+	X uint8 `json:"x"`
+	Y uint8 `json:"y"`
+}
+
+// Cell is a non empty cell of the grid
+type Cell struct {
+	X     uint8  `json:"x"`
+	Y     uint8  `json:"y"`
+	Race  string `json:"race"`
+	Count uint8  `json:"count"`
+}
+
+// State is the request of POST /play
+type State struct {
+	Height uint8  `json:"height"`
+	Width  uint8  `json:"width"`
+	Cells  []Cell `json:"cells"`
+}
+
+// Move of N units from Start to End
+type Move struct {
+	Start Coordinates `json:"start"`
+	N     uint8       `json:"n"`
+	End   Coordinates `json:"end"`
+}
+
+// Coup is the response of POST /play
+type Coup struct {
+	Moves []Move `json:"moves"`
+}
+
+// NameResponse is the response of GET /name
+type NameResponse struct {
+	Name string `json:"name"`
+}
+
+// FromState converts a state to the schema, the cells are sorted by column then row
+func FromState(s *model.State) State {
+	res := State{Height: s.Height, Width: s.Width, Cells: []Cell{}}
+	for coords, cell := range s.Grid {
+		if cell.IsEmpty() {
+			continue
+		}
+		res.Cells = append(res.Cells, Cell{X: coords.X, Y: coords.Y, Race: raceNames[cell.Race], Count: cell.Count})
+	}
+	sort.Slice(res.Cells, func(i, j int) bool {
+		a, b := res.Cells[i], res.Cells[j]
+		return a.X < b.X || (a.X == b.X && a.Y < b.Y)
+	})
+	return res
+}
+
+// ToState converts a state of the schema, it checks that the cells fit in the grid, are occupied and listed once
+func (s State) ToState() (*model.State, error) {
+	state := model.NewState(s.Height, s.Width)
+	for _, cell := range s.Cells {
+		if cell.X >= s.Width || cell.Y >= s.Height {
+			return nil, fmt.Errorf("cell (%d, %d) is out of the %dx%d grid", cell.X, cell.Y, s.Height, s.Width)
+		}
+		if cell.Count == 0 {
+			return nil, fmt.Errorf("cell (%d, %d) is empty, only the occupied cells are listed", cell.X, cell.Y)
+		}
+		if _, ok := state.Grid[model.Coordinates{X: cell.X, Y: cell.Y}]; ok {
+			return nil, fmt.Errorf("cell (%d, %d) is listed twice", cell.X, cell.Y)
+		}
+		var race model.Race
+		switch cell.Race {
+		case RaceNeutral:
+			race = model.Neutral
+		case RaceAlly:
+			race = model.Ally
+		case RaceEnemy:
+			race = model.Enemy
+		default:
+			return nil, fmt.Errorf("cell (%d, %d) has an unknown race %q", cell.X, cell.Y, cell.Race)
+		}
+		state.SetCell(model.Coordinates{X: cell.X, Y: cell.Y}, race, cell.Count)
+	}
+	return state, nil
+}
+
+// FromCoup converts a coup to the schema
+func FromCoup(coup model.Coup) Coup {
+	res := Coup{Moves: make([]Move, len(coup))}
+	for i, move := range coup {
+		res.Moves[i] = Move{
+			Start: Coordinates{X: move.Start.X, Y: move.Start.Y},
+			N:     move.N,
+			End:   Coordinates{X: move.End.X, Y: move.End.Y},
+		}
+	}
+	return res
+}
+
+// ToCoup converts a coup of the schema
+func (c Coup) ToCoup() model.Coup {
+	res := make(model.Coup, len(c.Moves))
+	for i, move := range c.Moves {
+		res[i] = model.Move{
+			Start: model.Coordinates{X: move.Start.X, Y: move.Start.Y},
+			N:     move.N,
+			End:   model.Coordinates{X: move.End.X, Y: move.End.Y},
+		}
+	}
+	return res
+}
@@ -0,0 +1,243 @@
+package replay
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/tournament"
+	"github.com/langorou/langorou/pkg/utils"
+	"github.com/langorou/twilight/server"
+)
+
+// ExportOptions configures the rendering of the exported frames
+type ExportOptions struct {
+	// CellSize is the size of a cell in pixels
+	CellSize int
+	// Delay between two frames of a GIF, in 100ths of a second
+	Delay int
+}
+
+// DefaultExportOptions returns the options used by cmd/replay
+func DefaultExportOptions() ExportOptions {
+	return ExportOptions{CellSize: 40, Delay: 50}
+}
+
+var (
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorGrid       = color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+	colorText       = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	colorCount      = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	// Races colors, indexed by the race of the first player's point of view
+	raceColors = map[model.Race]color.RGBA{
+		model.Neutral: {0xe0, 0x9a, 0x1b, 0xff},
+		model.Ally:    {0xc0, 0x27, 0x2d, 0xff},
+		model.Enemy:   {0x23, 0x4e, 0xa8, 0xff},
+	}
+)
+
+var palette = color.Palette{
+	colorBackground,
+	colorGrid,
+	colorText,
+	colorCount,
+	raceColors[model.Neutral],
+	raceColors[model.Ally],
+	raceColors[model.Enemy],
+}
+
+// headerSize returns the height in pixels of the band above the grid showing the turn and the populations
+func (o ExportOptions) headerSize() int {
+	return glyphHeight*o.scale() + o.CellSize/2
+}
+
+// scale returns the scale used for the texts
+func (o ExportOptions) scale() int {
+	if o.CellSize < 30 {
+		return 1
+	}
+	return 2
+}
+
+type headerPart struct {
+	text  string
+	color color.Color
+}
+
+// header gives the texts shown above the grid: turn number and population of each race, they are padded
+// so that every frame of a match has the same size
+func header(p server.Packed) []headerPart {
+	humans, wolves, vampires := tournament.Population(p)
+	return []headerPart{
+		{fmt.Sprintf("T%-3d", p.Mov), colorText},
+		{fmt.Sprintf("W:%-4d", wolves), raceColors[model.Ally]},
+		{fmt.Sprintf("V:%-4d", vampires), raceColors[model.Enemy]},
+		{fmt.Sprintf("H:%-4d", humans), raceColors[model.Neutral]},
+	}
+}
+
+// RenderFrame draws a frame of the history
+func RenderFrame(p server.Packed, opts ExportOptions) *image.Paletted {
+	rows, columns := tournament.FrameSize(p)
+	size, top, scale := opts.CellSize, opts.headerSize(), opts.scale()
+
+	// The header may be wider than the grid on small maps
+	parts := header(p)
+	width := size / 4
+	for _, part := range parts {
+		width += textWidth(part.text, scale) + (glyphWidth+1)*scale
+	}
+	if width < columns*size+1 {
+		width = columns*size + 1
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, width, top+rows*size+1), palette)
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+
+	x := size / 4
+	for _, part := range parts {
+		drawText(img, part.text, x, size/4, scale, part.color)
+		x += textWidth(part.text, scale) + (glyphWidth+1)*scale
+	}
+
+	grid := image.NewUniform(colorGrid)
+	for col := 0; col <= columns; col++ {
+		draw.Draw(img, image.Rect(col*size, top, col*size+1, top+rows*size+1), grid, image.Point{}, draw.Src)
+	}
+	for row := 0; row <= rows; row++ {
+		draw.Draw(img, image.Rect(0, top+row*size, columns*size+1, top+row*size+1), grid, image.Point{}, draw.Src)
+	}
+
+	state := tournament.StateFromPacked(p, tournament.Player1)
+	margin := size / 10
+	for coords, cell := range state.Grid {
+		x0, y0 := int(coords.X)*size, top+int(coords.Y)*size
+		rect := image.Rect(x0+margin+1, y0+margin+1, x0+size-margin+1, y0+size-margin+1)
+		draw.Draw(img, rect, image.NewUniform(raceColors[cell.Race]), image.Point{}, draw.Src)
+
+		count := fmt.Sprintf("%d", cell.Count)
+		drawText(
+			img, count,
+			x0+(size-textWidth(count, scale))/2+1, y0+(size-glyphHeight*scale)/2+1,
+			scale, colorCount,
+		)
+	}
+
+	return img
+}
+
+// WriteGIF writes the whole history of a match as an animated GIF
+func WriteGIF(w io.Writer, mr *tournament.MatchSummary, opts ExportOptions) error {
+	if len(mr.History) == 0 {
+		return fmt.Errorf("empty history, nothing to export")
+	}
+
+	anim := &gif.GIF{}
+	for _, p := range mr.History {
+		anim.Image = append(anim.Image, RenderFrame(p, opts))
+		anim.Delay = append(anim.Delay, opts.Delay)
+	}
+	// Stay longer on the final position
+	anim.Delay[len(anim.Delay)-1] = 4 * opts.Delay
+
+	return gif.EncodeAll(w, anim)
+}
+
+// RenderSVG writes a frame of the history as an SVG document
+func RenderSVG(w io.Writer, p server.Packed, opts ExportOptions) error {
+	rows, columns := tournament.FrameSize(p)
+	size := opts.CellSize
+	top := opts.headerSize()
+	fontSize := size * 2 / 5
+
+	// The header may be wider than the grid on small maps
+	parts := header(p)
+	width := size / 4
+	for _, part := range parts {
+		width += (len(part.text) + 2) * fontSize * 3 / 5
+	}
+	if width < columns*size+1 {
+		width = columns*size + 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(
+		&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-weight="bold">`+"\n",
+		width, top+rows*size+1,
+	)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hex(colorBackground))
+
+	x := size / 4
+	for _, part := range parts {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="%d" fill="%s">%s</text>`+"\n", x, top-size/4, fontSize, hex(part.color), strings.TrimSpace(part.text))
+		x += (len(part.text) + 2) * fontSize * 3 / 5
+	}
+
+	for col := 0; col <= columns; col++ {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s"/>`+"\n", col*size, top, col*size, top+rows*size, hex(colorGrid))
+	}
+	for row := 0; row <= rows; row++ {
+		fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="%s"/>`+"\n", top+row*size, columns*size, top+row*size, hex(colorGrid))
+	}
+
+	state := tournament.StateFromPacked(p, tournament.Player1)
+	margin := size / 10
+	// Iterate over the grid in order to produce the same document for the same frame
+	for row := uint8(0); row < state.Height; row++ {
+		for col := uint8(0); col < state.Width; col++ {
+			cell, ok := state.Grid[model.Coordinates{X: col, Y: row}]
+			if !ok || cell.IsEmpty() {
+				continue
+			}
+			x0, y0 := int(col)*size, top+int(row)*size
+			fmt.Fprintf(
+				&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+				x0+margin, y0+margin, size-2*margin, size-2*margin, hex(raceColors[cell.Race]),
+			)
+			fmt.Fprintf(
+				&b, `<text x="%d" y="%d" font-size="%d" fill="%s" text-anchor="middle" dominant-baseline="central">%d</text>`+"\n",
+				x0+size/2, y0+size/2, fontSize, hex(colorCount), cell.Count,
+			)
+		}
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSVGFrames writes every frame of the history as an SVG file in dir, named frame_<index>.svg
+func WriteSVGFrames(dir string, mr *tournament.MatchSummary, opts ExportOptions) error {
+	if err := utils.CreateDirIfNotExist(dir); err != nil {
+		return err
+	}
+
+	for i, p := range mr.History {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("frame_%03d.svg", i)))
+		if err != nil {
+			return err
+		}
+
+		err = RenderSVG(f, p, opts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
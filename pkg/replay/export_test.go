@@ -0,0 +1,56 @@
+package replay
+
+import (
+	"bytes"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteGIF(t *testing.T) {
+	mr := loadTestReplay(t)
+	opts := DefaultExportOptions()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGIF(&buf, mr, opts))
+
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	assert.Len(t, anim.Image, len(mr.History))
+
+	// 3x3 grid with the header on top, the header is wider than the grid
+	bounds := anim.Image[0].Bounds()
+	assert.True(t, bounds.Dx() > 3*opts.CellSize+1)
+	assert.Equal(t, opts.headerSize()+3*opts.CellSize+1, bounds.Dy())
+
+	// Humans are at (1, 1), look inside their cell but outside of the text
+	size, top := opts.CellSize, opts.headerSize()
+	img := anim.Image[0]
+	assert.Equal(t, raceColors[model.Neutral], img.At(size+size/5, top+size+size/5))
+	assert.Equal(t, colorBackground, img.At(size/5, top+size+size/5))
+}
+
+func TestWriteSVGFrames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "langorou_svg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, WriteSVGFrames(dir, loadTestReplay(t), DefaultExportOptions()))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.svg"))
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	last, err := ioutil.ReadFile(filepath.Join(dir, "frame_002.svg"))
+	require.NoError(t, err)
+	assert.Contains(t, string(last), ">T2</text>")
+	assert.Contains(t, string(last), ">V:10</text>")
+	assert.Contains(t, string(last), ">H:0</text>")
+	assert.Contains(t, string(last), ">10</text>")
+}
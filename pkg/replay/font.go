@@ -0,0 +1,63 @@
+package replay
+
+import (
+	"image"
+	"image/color"
+)
+
+// The standard library doesn't come with fonts, so we draw the few characters we need from 3x5 bitmaps
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+var glyphs = map[rune][glyphHeight]string{
+	'0': {"XXX", "X.X", "X.X", "X.X", "XXX"},
+	'1': {".X.", "XX.", ".X.", ".X.", "XXX"},
+	'2': {"XXX", "..X", "XXX", "X..", "XXX"},
+	'3': {"XXX", "..X", ".XX", "..X", "XXX"},
+	'4': {"X.X", "X.X", "XXX", "..X", "..X"},
+	'5': {"XXX", "X..", "XXX", "..X", "XXX"},
+	'6': {"XXX", "X..", "XXX", "X.X", "XXX"},
+	'7': {"XXX", "..X", "..X", ".X.", ".X."},
+	'8': {"XXX", "X.X", "XXX", "X.X", "XXX"},
+	'9': {"XXX", "X.X", "XXX", "..X", "XXX"},
+	'T': {"XXX", ".X.", ".X.", ".X.", ".X."},
+	'W': {"X.X", "X.X", "X.X", "XXX", "X.X"},
+	'V': {"X.X", "X.X", "X.X", "X.X", ".X."},
+	'H': {"X.X", "X.X", "XXX", "X.X", "X.X"},
+	':': {"...", ".X.", "...", ".X.", "..."},
+	'/': {"..X", "..X", ".X.", "X..", "X.."},
+	' ': {"...", "...", "...", "...", "..."},
+}
+
+// textWidth returns the width in pixels of a text drawn with drawText
+func textWidth(text string, scale int) int {
+	if len(text) == 0 {
+		return 0
+	}
+	return (len(text)*(glyphWidth+1) - 1) * scale
+}
+
+// drawText draws text with its top left corner at (x, y), unknown characters are skipped
+func drawText(img *image.Paletted, text string, x, y, scale int, c color.Color) {
+	idx := uint8(img.Palette.Index(c))
+	for _, r := range text {
+		glyph, ok := glyphs[r]
+		if ok {
+			for row, line := range glyph {
+				for col, pixel := range line {
+					if pixel != 'X' {
+						continue
+					}
+					for dy := 0; dy < scale; dy++ {
+						for dx := 0; dx < scale; dx++ {
+							img.SetColorIndex(x+col*scale+dx, y+row*scale+dy, idx)
+						}
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
@@ -0,0 +1,167 @@
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/langorou/pkg/tournament"
+)
+
+// ANSI escape codes used to color the races
+const (
+	colorReset    = "\033[0m"
+	colorHumans   = "\033[33m"
+	colorWolves   = "\033[31m"
+	colorVampires = "\033[34m"
+	clearScreen   = "\033[H\033[2J"
+)
+
+const viewerHelp = `commands:
+  n, <enter>   next turn
+  p, b         previous turn
+  g <N>, <N>   jump to turn N
+  f, l         first / last turn
+  h            this help
+  q            quit
+`
+
+// Viewer steps through the history of a match in a terminal, frames are shown from the point of view of
+// the first player: werewolves are W, vampires V and humans H
+type Viewer struct {
+	mr    *tournament.MatchSummary
+	in    *bufio.Scanner
+	out   io.Writer
+	color bool
+	frame int
+}
+
+// NewViewer creates a viewer reading commands from in and rendering the frames to out
+func NewViewer(mr *tournament.MatchSummary, in io.Reader, out io.Writer, color bool) *Viewer {
+	return &Viewer{mr: mr, in: bufio.NewScanner(in), out: out, color: color}
+}
+
+func (v *Viewer) paint(color, s string) string {
+	if !v.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (v *Viewer) cellRepr(cell model.Cell) string {
+	switch cell.Race {
+	case model.Ally:
+		return v.paint(colorWolves, fmt.Sprintf("%3dW", cell.Count))
+	case model.Enemy:
+		return v.paint(colorVampires, fmt.Sprintf("%3dV", cell.Count))
+	default:
+		return v.paint(colorHumans, fmt.Sprintf("%3dH", cell.Count))
+	}
+}
+
+// Render renders the current frame with a header giving the turn and the population totals
+func (v *Viewer) Render() string {
+	p := v.mr.History[v.frame]
+	humans, wolves, vampires := tournament.Population(p)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", v.mr.String())
+	fmt.Fprintf(&b, "turn %d/%d (frame %d/%d) %s\n", p.Mov, v.mr.History[len(v.mr.History)-1].Mov, v.frame, len(v.mr.History)-1, p.State)
+	fmt.Fprintf(
+		&b, "%s: %d | %s: %d | %s: %d\n",
+		v.paint(colorWolves, "werewolves (P1) "+v.mr.Player1.Name()), wolves,
+		v.paint(colorVampires, "vampires (P2) "+v.mr.Player2.Name()), vampires,
+		v.paint(colorHumans, "humans"), humans,
+	)
+	b.WriteString(tournament.StateFromPacked(p, tournament.Player1).Render(v.cellRepr))
+	b.WriteString("|\n")
+
+	return b.String()
+}
+
+// Seek moves to the given frame, clamped to the history bounds
+func (v *Viewer) Seek(frame int) {
+	if frame < 0 {
+		frame = 0
+	} else if frame >= len(v.mr.History) {
+		frame = len(v.mr.History) - 1
+	}
+	v.frame = frame
+}
+
+// SeekTurn moves to the last frame whose turn is lower or equal to turn
+func (v *Viewer) SeekTurn(turn int) {
+	frame := 0
+	for i, p := range v.mr.History {
+		if p.Mov <= turn {
+			frame = i
+		}
+	}
+	v.frame = frame
+}
+
+// Frame returns the index of the current frame
+func (v *Viewer) Frame() int {
+	return v.frame
+}
+
+// Run renders the frames and executes the commands until the input is closed or the user quits
+func (v *Viewer) Run() error {
+	if len(v.mr.History) == 0 {
+		return fmt.Errorf("empty history, nothing to show")
+	}
+
+	// message is displayed below the frame
+	var message string
+	for {
+		if v.color {
+			io.WriteString(v.out, clearScreen)
+		}
+		if _, err := fmt.Fprintf(v.out, "%s%s\n[n]ext [p]rev [g N] [f]irst [l]ast [h]elp [q]uit > ", v.Render(), message); err != nil {
+			return err
+		}
+		message = ""
+
+		if !v.in.Scan() {
+			return v.in.Err()
+		}
+
+		fields := strings.Fields(v.in.Text())
+		if len(fields) == 0 {
+			v.Seek(v.frame + 1)
+			continue
+		}
+
+		switch fields[0] {
+		case "n":
+			v.Seek(v.frame + 1)
+		case "p", "b":
+			v.Seek(v.frame - 1)
+		case "f":
+			v.Seek(0)
+		case "l":
+			v.Seek(len(v.mr.History) - 1)
+		case "q":
+			return nil
+		case "h":
+			message = viewerHelp
+		case "g":
+			if len(fields) < 2 {
+				message = "usage: g <turn>\n"
+				continue
+			}
+			fields = fields[1:]
+			fallthrough
+		default:
+			turn, err := strconv.Atoi(fields[0])
+			if err != nil {
+				message = fmt.Sprintf("unknown command %q\n%s", fields[0], viewerHelp)
+				continue
+			}
+			v.SeekTurn(turn)
+		}
+	}
+}
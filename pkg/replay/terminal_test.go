@@ -0,0 +1,67 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/langorou/langorou/pkg/tournament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testReplay = `{
+	"MapName": "test",
+	"Player1": {"Dumb": true},
+	"Player2": {"Dumb": true},
+	"History": [
+		{"X": 241, "Y": 241, "Humans": [{"c": 4, "X": 80, "Y": 80}], "Wolfs": [{"c": 6, "X": 0, "Y": 0}], "Vamps": [{"c": 6, "X": 160, "Y": 160}], "Mov": 0},
+		{"X": 241, "Y": 241, "Humans": [{"c": 4, "X": 80, "Y": 80}], "Wolfs": [{"c": 6, "X": 0, "Y": 80}], "Vamps": [{"c": 6, "X": 160, "Y": 160}], "Mov": 1},
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 6, "X": 0, "Y": 80}], "Vamps": [{"c": 10, "X": 80, "Y": 80}], "Mov": 2, "State": "Playing"}
+	]
+}`
+
+func loadTestReplay(t *testing.T) *tournament.MatchSummary {
+	var mr tournament.MatchSummary
+	require.NoError(t, json.Unmarshal([]byte(testReplay), &mr))
+	return &mr
+}
+
+func TestViewerRender(t *testing.T) {
+	v := NewViewer(loadTestReplay(t), nil, nil, false)
+
+	out := v.Render()
+	assert.Contains(t, out, "turn 0/2 (frame 0/2)")
+	assert.Contains(t, out, "dumb IA: 6 |")
+	assert.Contains(t, out, "humans: 4")
+	assert.Contains(t, out, "\n|   6W |      |      |\n|      |   4H |      |\n|      |      |   6V |\n")
+}
+
+func TestViewerCommands(t *testing.T) {
+	in := strings.NewReader("n\n\np\ng 2\nf\n1\nl\nfoo\nq\nn\n")
+	var out bytes.Buffer
+	v := NewViewer(loadTestReplay(t), in, &out, false)
+
+	require.NoError(t, v.Run())
+	// q stopped the viewer before the last command
+	assert.Equal(t, 2, v.Frame())
+	assert.Contains(t, out.String(), `unknown command "foo"`)
+
+	for _, turn := range []string{"turn 0/2", "turn 1/2", "turn 2/2"} {
+		assert.Contains(t, out.String(), turn)
+	}
+}
+
+func TestViewerSeek(t *testing.T) {
+	v := NewViewer(loadTestReplay(t), nil, nil, false)
+
+	v.Seek(-3)
+	assert.Equal(t, 0, v.Frame())
+	v.Seek(12)
+	assert.Equal(t, 2, v.Frame())
+	v.SeekTurn(1)
+	assert.Equal(t, 1, v.Frame())
+	v.SeekTurn(42)
+	assert.Equal(t, 2, v.Frame())
+}
@@ -0,0 +1,313 @@
+package tournament
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MatchObserver is notified of the progress of the matches of a tournament, matches are identified by the unique ID
+// assigned when they are queued, which is also the ID of their summary
+type MatchObserver interface {
+	MatchQueued(id string, p1, p2 Participant, mapName string)
+	MatchStarted(id string)
+	MatchFailed(id string, err error)
+}
+
+// MatchStatus describes a match which is not finished
+type MatchStatus struct {
+	Name    string
+	Player1 string
+	Player2 string
+	Map     string
+	Running bool
+	// Started is the time at which the match started, zero if it is queued
+	Started time.Time
+	queued  int
+}
+
+// DashboardSnapshot is the state of the tournament sent to the dashboard
+type DashboardSnapshot struct {
+	Running  []MatchStatus
+	Queued   []MatchStatus
+	Failed   []string
+	Finished *Report
+}
+
+const dashboardReplayDir = "replays"
+
+// Dashboard tracks the matches of a tournament and serves a live view over HTTP, updates are pushed with server-sent events
+type Dashboard struct {
+	mu       sync.Mutex
+	pending  map[string]*MatchStatus
+	failed   []string
+	finished Result
+	replays  map[string]*MatchSummary
+	queued   int
+
+	subscribers map[chan struct{}]bool
+}
+
+// NewDashboard creates an empty dashboard
+func NewDashboard() *Dashboard {
+	return &Dashboard{
+		pending:     map[string]*MatchStatus{},
+		replays:     map[string]*MatchSummary{},
+		subscribers: map[chan struct{}]bool{},
+	}
+}
+
+// MatchQueued implements MatchObserver
+func (d *Dashboard) MatchQueued(id string, p1, p2 Participant, mapName string) {
+	d.mu.Lock()
+	d.queued++
+	d.pending[id] = &MatchStatus{Name: id, Player1: p1.Name(), Player2: p2.Name(), Map: mapName, queued: d.queued}
+	d.mu.Unlock()
+	d.notify()
+}
+
+// MatchStarted implements MatchObserver
+func (d *Dashboard) MatchStarted(id string) {
+	d.mu.Lock()
+	if m, ok := d.pending[id]; ok {
+		m.Running = true
+		m.Started = time.Now()
+	}
+	d.mu.Unlock()
+	d.notify()
+}
+
+// MatchFailed implements MatchObserver
+func (d *Dashboard) MatchFailed(id string, err error) {
+	d.mu.Lock()
+	delete(d.pending, id)
+	d.failed = append(d.failed, fmt.Sprintf("%s: %s", id, err))
+	d.mu.Unlock()
+	d.notify()
+}
+
+// MatchFinished records the summary of a finished match, it is meant to be called with the summaries received on the
+// channel given to RunTournamentOnMap
+func (d *Dashboard) MatchFinished(mr MatchSummary) {
+	d.mu.Lock()
+	delete(d.pending, mr.ID)
+	d.finished = append(d.finished, mr)
+	d.replays[mr.replayName()] = &mr
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Snapshot returns the current state of the tournament
+func (d *Dashboard) Snapshot() DashboardSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := DashboardSnapshot{
+		Failed:   append([]string{}, d.failed...),
+		Finished: d.finished.Report(dashboardReplayDir),
+	}
+	for _, m := range d.pending {
+		if m.Running {
+			snapshot.Running = append(snapshot.Running, *m)
+		} else {
+			snapshot.Queued = append(snapshot.Queued, *m)
+		}
+	}
+	sort.Slice(snapshot.Running, func(i, j int) bool { return snapshot.Running[i].Started.Before(snapshot.Running[j].Started) })
+	sort.Slice(snapshot.Queued, func(i, j int) bool { return snapshot.Queued[i].queued < snapshot.Queued[j].queued })
+
+	return snapshot
+}
+
+// notify wakes up the subscribers without blocking, a subscriber which is already notified will send the latest state
+func (d *Dashboard) notify() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for ch := range d.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *Dashboard) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{} // send the current state right away
+
+	d.mu.Lock()
+	d.subscribers[ch] = true
+	d.mu.Unlock()
+	return ch
+}
+
+func (d *Dashboard) unsubscribe(ch chan struct{}) {
+	d.mu.Lock()
+	delete(d.subscribers, ch)
+	d.mu.Unlock()
+}
+
+// Handler returns the HTTP handler of the dashboard:
+//   - / serves the page
+//   - /state serves the current snapshot as JSON
+//   - /events streams the snapshots as server-sent events
+//   - /replays/<name>.lgr serves the replay of a finished match
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, nil); err != nil {
+			log.Printf("dashboard: %s", err)
+		}
+	})
+
+	mux.HandleFunc("/state", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.Snapshot()); err != nil {
+			log.Printf("dashboard: %s", err)
+		}
+	})
+
+	mux.HandleFunc("/events", d.serveEvents)
+
+	mux.HandleFunc("/"+dashboardReplayDir+"/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/"+dashboardReplayDir+"/")
+
+		d.mu.Lock()
+		mr, ok := d.replays[name]
+		d.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		if err := EncodeReplay(w, mr); err != nil {
+			log.Printf("dashboard: %s", err)
+		}
+	})
+
+	return mux
+}
+
+func (d *Dashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ch := d.subscribe()
+	defer d.unsubscribe(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			data, err := json.Marshal(d.Snapshot())
+			if err != nil {
+				log.Printf("dashboard: %s", err)
+				return
+			}
+			if _, err = fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Serve starts the dashboard on addr, it blocks like http.ListenAndServe
+func (d *Dashboard) Serve(addr string) error {
+	return http.ListenAndServe(addr, d.Handler())
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Tournament dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: right; }
+th { background: #eee; }
+td.name { text-align: left; }
+#status { color: #888; }
+</style>
+</head>
+<body>
+<h1>Tournament dashboard <small id="status">connecting...</small></h1>
+
+<h2>Leaderboard</h2>
+<table id="leaderboard"></table>
+
+<h2>Running matches</h2>
+<table id="running"></table>
+
+<h2>Queued matches</h2>
+<table id="queued"></table>
+
+<h2>Finished matches</h2>
+<table id="finished"></table>
+
+<h2>Failed matches</h2>
+<ul id="failed"></ul>
+
+<script>
+function esc(s) {
+	var div = document.createElement("div");
+	div.textContent = String(s);
+	return div.innerHTML;
+}
+
+function table(id, headers, rows) {
+	var html = "<tr>" + headers.map(function (h) { return "<th>" + esc(h) + "</th>"; }).join("") + "</tr>";
+	rows.forEach(function (row) {
+		html += "<tr>" + row.map(function (c, i) { return "<td" + (i === 0 || typeof c === "string" ? " class=\"name\"" : "") + ">" + c + "</td>"; }).join("") + "</tr>";
+	});
+	document.getElementById(id).innerHTML = html;
+}
+
+function render(s) {
+	var now = Date.now();
+	table("leaderboard", ["Participant", "Points", "Wins", "Draws", "Losses", "Avg turns"], (s.Finished.Standings || []).map(function (p) {
+		return [esc(p.Name), p.Points, p.Wins, p.Draws, p.Losses, p.AvgTurns.toFixed(1)];
+	}));
+	table("running", ["Player 1", "Player 2", "Map", "Running for"], (s.Running || []).map(function (m) {
+		return [esc(m.Player1), esc(m.Player2), esc(m.Map), Math.round((now - Date.parse(m.Started)) / 1000) + "s"];
+	}));
+	table("queued", ["Player 1", "Player 2", "Map"], (s.Queued || []).map(function (m) {
+		return [esc(m.Player1), esc(m.Player2), esc(m.Map)];
+	}));
+	table("finished", ["Player 1", "Player 2", "Map", "Score", "Turns", "Replay"], (s.Finished.Matches || []).slice().reverse().map(function (m) {
+		return [esc(m.Player1), esc(m.Player2), esc(m.Map), m.Player1Eff + " - " + m.Player2Eff, m.Turns, "<a href=\"" + encodeURI(m.Replay) + "\">replay</a>"];
+	}));
+	document.getElementById("failed").innerHTML = (s.Failed || []).map(function (f) { return "<li>" + esc(f) + "</li>"; }).join("");
+}
+
+var source = new EventSource("events");
+source.onopen = function () { document.getElementById("status").textContent = "live"; };
+source.onerror = function () { document.getElementById("status").textContent = "disconnected, retrying..."; };
+source.onmessage = function (e) { render(JSON.parse(e.data)); };
+</script>
+</body>
+</html>
+`))
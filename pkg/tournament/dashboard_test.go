@@ -0,0 +1,93 @@
+package tournament
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	mr, err := LoadMatchSummary(testReplayPath)
+	require.NoError(t, err)
+	mr.ID = newMatchID(mr.shortName())
+	name := mr.ID
+	// A match with the same players on a map with the same name, like two random maps with the same parameters
+	twin := newMatchID(mr.shortName())
+
+	d := NewDashboard()
+	d.MatchQueued(name, mr.Player1, mr.Player2, mr.MapName)
+	d.MatchQueued(twin, mr.Player1, mr.Player2, mr.MapName)
+	d.MatchQueued("broken", mr.Player2, mr.Player1, "maps/broken.xml")
+
+	snapshot := d.Snapshot()
+	assert.Len(t, snapshot.Queued, 3)
+	assert.Empty(t, snapshot.Running)
+	assert.Equal(t, name, snapshot.Queued[0].Name)
+	assert.Equal(t, twin, snapshot.Queued[1].Name)
+
+	d.MatchStarted(name)
+	d.MatchStarted("broken")
+	d.MatchFailed("broken", fmt.Errorf("connection refused"))
+	snapshot = d.Snapshot()
+	require.Len(t, snapshot.Running, 1)
+	assert.Equal(t, "dumb IA", snapshot.Running[0].Player1)
+	assert.Len(t, snapshot.Queued, 1)
+	assert.Equal(t, []string{"broken: connection refused"}, snapshot.Failed)
+
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	// Subscribe before the end of the match, the first event is the current state
+	resp, err := http.Get(srv.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := bufio.NewReader(resp.Body)
+
+	readEvent := func() DashboardSnapshot {
+		line, err := events.ReadString('\n')
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(line, "data: "), line)
+		_, err = events.ReadString('\n')
+		require.NoError(t, err)
+
+		var s DashboardSnapshot
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &s))
+		return s
+	}
+
+	assert.Len(t, readEvent().Running, 1)
+
+	d.MatchFinished(*mr)
+	s := readEvent()
+	assert.Empty(t, s.Running)
+	// The twin match is still queued
+	require.Len(t, s.Queued, 1)
+	assert.Equal(t, twin, s.Queued[0].Name)
+	require.Len(t, s.Finished.Matches, 1)
+	require.Len(t, s.Finished.Standings, 2)
+	assert.Equal(t, 1, s.Finished.Standings[0].Wins)
+	assert.Equal(t, 14., s.Finished.Standings[0].AvgTurns)
+
+	// The replay of the finished match can be downloaded
+	replay, err := http.Get(srv.URL + "/" + (&url.URL{Path: s.Finished.Matches[0].Replay}).EscapedPath())
+	require.NoError(t, err)
+	defer replay.Body.Close()
+	require.Equal(t, http.StatusOK, replay.StatusCode)
+	decoded, err := DecodeReplay(replay.Body)
+	require.NoError(t, err)
+	assert.Len(t, decoded.History, len(mr.History))
+
+	missing, err := http.Get(srv.URL + "/replays/missing.lgr")
+	require.NoError(t, err)
+	missing.Body.Close()
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
@@ -0,0 +1,309 @@
+package tournament
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/twilight/server"
+)
+
+// MatchSummaryVersion is the version of the match summaries written by Save, files written before versioning
+// was introduced have the version 0 and share the same format, the version 2 added the seed and the version 3 the duration
+const MatchSummaryVersion = 3
+
+// CorruptReplayError describes where and why a replay is invalid
+type CorruptReplayError struct {
+	// Frame is the index in the history of the invalid frame, -1 if the error is not related to a frame
+	Frame  int
+	Reason string
+}
+
+func (e *CorruptReplayError) Error() string {
+	if e.Frame < 0 {
+		return fmt.Sprintf("corrupt replay: %s", e.Reason)
+	}
+	return fmt.Sprintf("corrupt replay at frame %d: %s", e.Frame, e.Reason)
+}
+
+func corrupt(frame int, format string, args ...interface{}) error {
+	return &CorruptReplayError{Frame: frame, Reason: fmt.Sprintf(format, args...)}
+}
+
+// LoadMatchSummary reads a replay file and validates it, see DecodeMatchSummary
+func LoadMatchSummary(path string) (*MatchSummary, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mr, err := DecodeMatchSummary(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return mr, nil
+}
+
+// DecodeMatchSummary decodes a replay, either JSON or in the compact format, and validates it: the schema and
+// version of the file, the consistency of the outcome with the history and that each frame of the history can
+// be reached from the previous one
+func DecodeMatchSummary(data []byte) (*MatchSummary, error) {
+	if IsCompactReplay(data) {
+		mr, err := DecodeReplay(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if err = mr.Validate(); err != nil {
+			return nil, err
+		}
+		return mr, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var mr MatchSummary
+	if err := dec.Decode(&mr); err != nil {
+		if serr, ok := err.(*json.SyntaxError); ok {
+			return nil, corrupt(-1, "invalid JSON at byte %d: %s", serr.Offset, serr)
+		}
+		return nil, corrupt(-1, "invalid JSON: %s", err)
+	}
+	if dec.More() {
+		return nil, corrupt(-1, "unexpected data after the replay at byte %d", dec.InputOffset())
+	}
+
+	if err := mr.Validate(); err != nil {
+		return nil, err
+	}
+	return &mr, nil
+}
+
+// Validate checks that the match summary is consistent, errors are *CorruptReplayError
+func (mr *MatchSummary) Validate() error {
+	if mr.Version < 0 || mr.Version > MatchSummaryVersion {
+		return corrupt(-1, "unsupported version %d, latest supported is %d", mr.Version, MatchSummaryVersion)
+	}
+	if mr.Winner != tie && mr.Winner != player1Won && mr.Winner != player2Won {
+		return corrupt(-1, "invalid winner code %d", mr.Winner)
+	}
+	if len(mr.History) == 0 {
+		return corrupt(-1, "empty history")
+	}
+
+	for i, p := range mr.History {
+		if err := validateFrame(i, p, mr.History[0]); err != nil {
+			return err
+		}
+		if i > 0 {
+			if err := validateTransition(i, mr.History[i-1], p); err != nil {
+				return err
+			}
+		}
+	}
+
+	last := mr.History[len(mr.History)-1]
+	if last.Mov != mr.EndTurn {
+		return corrupt(-1, "end turn is %d but the last frame is at turn %d", mr.EndTurn, last.Mov)
+	}
+	_, werewolves, vampires := Population(last)
+	if werewolves != mr.Player1Eff || vampires != mr.Player2Eff {
+		return corrupt(
+			-1, "final populations are %d - %d but the last frame has %d werewolves and %d vampires",
+			mr.Player1Eff, mr.Player2Eff, werewolves, vampires,
+		)
+	}
+	winner := tie
+	if werewolves > vampires {
+		winner = player1Won
+	} else if vampires > werewolves {
+		winner = player2Won
+	}
+	if winner != mr.Winner {
+		return corrupt(-1, "winner code is %d but the final populations give %d", mr.Winner, winner)
+	}
+
+	return nil
+}
+
+// validateFrame checks that a frame is well formed and has the same dimensions as the first one
+func validateFrame(i int, p server.Packed, first server.Packed) error {
+	if p.X <= 1 || p.Y <= 1 || (p.X-1)%cellSize != 0 || (p.Y-1)%cellSize != 0 {
+		return corrupt(i, "invalid grid size %dx%d", p.X, p.Y)
+	}
+	if p.X != first.X || p.Y != first.Y {
+		return corrupt(i, "grid size changed from %dx%d to %dx%d", first.X, first.Y, p.X, p.Y)
+	}
+	rows, columns := FrameSize(p)
+	if rows > 255 || columns > 255 {
+		return corrupt(i, "grid of %dx%d cells is too big", rows, columns)
+	}
+
+	occupied := map[[2]int]string{}
+	for _, c := range frameCells(p) {
+		if c.X%cellSize != 0 || c.Y%cellSize != 0 || c.X < 0 || c.Y < 0 || c.X/cellSize >= columns || c.Y/cellSize >= rows {
+			return corrupt(i, "%s at invalid position (%d, %d)", c.race, c.X, c.Y)
+		}
+		pos := [2]int{c.X / cellSize, c.Y / cellSize}
+		// Groups wiped out during a battle can be kept with a count of 0 by the server
+		if c.Count > 255 || c.Count < 0 {
+			return corrupt(i, "invalid count of %s at %v: %d", c.race, pos, c.Count)
+		}
+		if other, ok := occupied[pos]; ok {
+			return corrupt(i, "%s and %s on the same cell %v", other, c.race, pos)
+		}
+		occupied[pos] = c.race
+	}
+
+	return nil
+}
+
+type frameCell struct {
+	race        string
+	X, Y, Count int
+}
+
+// frameCells lists the cells of a frame with their race
+func frameCells(p server.Packed) []frameCell {
+	var res []frameCell
+	for _, c := range p.Humans {
+		res = append(res, frameCell{"humans", c.X, c.Y, c.Count})
+	}
+	for _, c := range p.Wolfs {
+		res = append(res, frameCell{"werewolves", c.X, c.Y, c.Count})
+	}
+	for _, c := range p.Vamps {
+		res = append(res, frameCell{"vampires", c.X, c.Y, c.Count})
+	}
+	return res
+}
+
+// validateTransition checks that a frame can be reached from the previous one by the moves of one player
+func validateTransition(i int, before, after server.Packed) error {
+	if after.Mov != before.Mov && after.Mov != before.Mov+1 {
+		return corrupt(i, "turn went from %d to %d", before.Mov, after.Mov)
+	}
+
+	// Look at the transition from both points of view, the one who moved is the Ally
+	var errs []error
+	for _, persp := range []Perspective{Player1, Player2} {
+		err := validateMoves(StateFromPacked(before, persp), StateFromPacked(after, persp))
+		if err == nil || err == errNoMove {
+			// The server accepts empty moves, and saves a frame even when the moves it received were invalid
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %s", persp, err))
+	}
+
+	return corrupt(i, "no legal moves lead to this frame (%s, %s)", errs[0], errs[1])
+}
+
+// errNoMove is returned by validateMoves when nothing changed
+var errNoMove = fmt.Errorf("no move")
+
+// validateMoves checks that only the Ally race moved between the two states and that the changes are legal
+func validateMoves(before, after *model.State) error {
+	sources := map[model.Coordinates]uint8{}
+	for c, cell := range before.Grid {
+		if cell.Race != model.Ally {
+			continue
+		}
+		a, ok := after.Grid[c]
+		if ok && a.Race != model.Ally {
+			return fmt.Errorf("cell %+v: allies were replaced by race %d while they were moving", c, a.Race)
+		}
+		if a.Count < cell.Count {
+			sources[c] = cell.Count - a.Count
+		}
+	}
+
+	changed := map[model.Coordinates]bool{}
+	for _, grid := range []map[model.Coordinates]model.Cell{before.Grid, after.Grid} {
+		for c := range grid {
+			if _, ok := sources[c]; !ok && before.Grid[c] != after.Grid[c] && !(before.Grid[c].Count == 0 && after.Grid[c].Count == 0) {
+				changed[c] = true
+			}
+		}
+	}
+
+	if len(sources) == 0 {
+		if len(changed) == 0 {
+			return errNoMove
+		}
+		return fmt.Errorf("cells changed while no units moved")
+	}
+
+	var left, arrived, converted int
+	for _, n := range sources {
+		left += int(n)
+	}
+
+	for c := range changed {
+		reachable := false
+		for src := range sources {
+			if c.Distance(src) <= 1 {
+				reachable = true
+				break
+			}
+		}
+		if !reachable {
+			return fmt.Errorf("cell %+v changed but no units could reach it", c)
+		}
+
+		b, a := before.Grid[c], after.Grid[c]
+		if a.Count != 0 && a.Race != model.Ally && (a.Race != b.Race || a.Count > b.Count) {
+			return fmt.Errorf("cell %+v: %d units of race %d became %d units of race %d", c, b.Count, b.Race, a.Count, a.Race)
+		}
+		if a.Race == model.Ally {
+			if b.Race == model.Ally || b.Count == 0 {
+				if a.Count < b.Count {
+					return fmt.Errorf("cell %+v: allies went from %d to %d", c, b.Count, a.Count)
+				}
+				arrived += int(a.Count - b.Count)
+			} else {
+				arrived += int(a.Count)
+				if b.Race == model.Neutral {
+					converted += int(b.Count)
+				}
+			}
+		}
+	}
+
+	if arrived > left+converted {
+		return fmt.Errorf("%d units arrived but only %d left their cell and %d humans were converted", arrived, left, converted)
+	}
+
+	return nil
+}
+
+// Mover returns the player whose moves lead from the previous frame to the given one. When the moves of both players
+// can, like when an attack was repelled, the players are assumed to take turns. ok is false if no legal moves lead to
+// the frame
+func (mr *MatchSummary) Mover(frame int) (persp Perspective, ok bool) {
+	if frame < 1 || frame >= len(mr.History) {
+		return Player1, false
+	}
+
+	var legal []Perspective
+	for _, p := range []Perspective{Player1, Player2} {
+		if validateMoves(StateFromPacked(mr.History[frame-1], p), StateFromPacked(mr.History[frame], p)) == nil {
+			legal = append(legal, p)
+		}
+	}
+
+	switch len(legal) {
+	case 0:
+		return Player1, false
+	case 1:
+		return legal[0], true
+	}
+	for previous := frame - 1; previous >= 1; previous-- {
+		if p, ok := mr.Mover(previous); ok {
+			return p.Opponent(), true
+		}
+	}
+	// The first player to connect plays first
+	return Player1, true
+}
@@ -0,0 +1,141 @@
+package tournament
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testReplayPath = "testdata/thetrap.json"
+
+// loadRawReplay loads the test replay as generic JSON so that tests can corrupt it
+func loadRawReplay(t *testing.T) map[string]interface{} {
+	data, err := ioutil.ReadFile(testReplayPath)
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	return raw
+}
+
+func frame(raw map[string]interface{}, i int) map[string]interface{} {
+	return raw["History"].([]interface{})[i].(map[string]interface{})
+}
+
+func assertCorrupt(t *testing.T, raw map[string]interface{}, frame int, reason string) {
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+
+	_, err = DecodeMatchSummary(data)
+	require.Error(t, err)
+	cerr, ok := err.(*CorruptReplayError)
+	require.True(t, ok, "unexpected error type %T: %s", err, err)
+	assert.Equal(t, frame, cerr.Frame)
+	assert.Contains(t, cerr.Reason, reason)
+}
+
+func TestLoadMatchSummary(t *testing.T) {
+	mr, err := LoadMatchSummary(testReplayPath)
+	require.NoError(t, err)
+
+	assert.Len(t, mr.History, 15)
+	assert.Equal(t, 14, mr.EndTurn)
+	assert.Equal(t, player2Won, mr.Winner)
+
+	_, err = LoadMatchSummary("testdata/missing.json")
+	assert.Error(t, err)
+}
+
+func TestDecodeMatchSummaryErrors(t *testing.T) {
+	t.Run("truncated", func(t *testing.T) {
+		data, err := ioutil.ReadFile(testReplayPath)
+		require.NoError(t, err)
+
+		_, err = DecodeMatchSummary(data[:len(data)/2])
+		require.Error(t, err)
+		assert.Equal(t, -1, err.(*CorruptReplayError).Frame)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		raw["Moves"] = []int{1, 2}
+		assertCorrupt(t, raw, -1, `unknown field "Moves"`)
+	})
+
+	t.Run("future version", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		raw["Version"] = MatchSummaryVersion + 1
+		assertCorrupt(t, raw, -1, "unsupported version")
+	})
+
+	t.Run("legacy version", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		delete(raw, "Version")
+		data, err := json.Marshal(raw)
+		require.NoError(t, err)
+
+		_, err = DecodeMatchSummary(data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("wrong outcome", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		raw["Player2Eff"] = 42
+		assertCorrupt(t, raw, -1, "final populations")
+	})
+
+	t.Run("grid size changed", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		frame(raw, 6)["X"] = 881
+		assertCorrupt(t, raw, 6, "grid size changed")
+	})
+
+	t.Run("two groups on a cell", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		f := frame(raw, 2)
+		f["Humans"] = append(f["Humans"].([]interface{}), f["Wolfs"].([]interface{})[0])
+		assertCorrupt(t, raw, 2, "on the same cell")
+	})
+
+	t.Run("teleportation", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		// The vampires at (3, 2) take the humans at (2, 2) between frames 3 and 4, move them far away instead
+		vampires := frame(raw, 4)["Vamps"].([]interface{})[0].(map[string]interface{})
+		vampires["X"] = 640.
+		assertCorrupt(t, raw, 4, "no legal moves lead to this frame")
+	})
+
+	t.Run("units created", func(t *testing.T) {
+		raw := loadRawReplay(t)
+		vampires := frame(raw, 4)["Vamps"].([]interface{})[0].(map[string]interface{})
+		vampires["c"] = 12.
+		assertCorrupt(t, raw, 4, "units arrived")
+	})
+}
+
+func TestMover(t *testing.T) {
+	var mr MatchSummary
+	require.NoError(t, json.Unmarshal([]byte(`{"History": [
+		{"X": 241, "Y": 241, "Wolfs": [{"c": 5, "X": 0, "Y": 0}], "Vamps": [{"c": 4, "X": 80, "Y": 160}], "Mov": 0},
+		{"X": 241, "Y": 241, "Wolfs": [{"c": 5, "X": 0, "Y": 80}], "Vamps": [{"c": 4, "X": 80, "Y": 160}], "Mov": 1},
+		{"X": 241, "Y": 241, "Wolfs": [{"c": 3, "X": 0, "Y": 80}], "Vamps": [], "Mov": 2},
+		{"X": 241, "Y": 241, "Wolfs": [{"c": 3, "X": 0, "Y": 80}], "Vamps": [], "Mov": 3}
+	]}`), &mr))
+
+	p, ok := mr.Mover(1)
+	assert.True(t, ok)
+	assert.Equal(t, Player1, p)
+
+	// The vampires attacked and lost, losing werewolves could also be a move of theirs: the players take turns
+	p, ok = mr.Mover(2)
+	assert.True(t, ok)
+	assert.Equal(t, Player2, p)
+
+	_, ok = mr.Mover(3)
+	assert.False(t, ok)
+	_, ok = mr.Mover(0)
+	assert.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package tournament
+
+import (
+	"fmt"
+
+	"github.com/langorou/langorou/pkg/client/model"
+	"github.com/langorou/twilight/server"
+)
+
+// cellSize is the size in pixels of a cell in the frames recorded by the twilight server
+const cellSize = 80
+
+// Perspective selects which player of a match is considered as the Ally race when decoding a replay
+type Perspective uint8
+
+const (
+	// Player1 is the first player to connect, it always plays the werewolves
+	Player1 Perspective = iota
+	// Player2 is the second player to connect, it always plays the vampires
+	Player2
+)
+
+func (p Perspective) String() string {
+	if p == Player1 {
+		return "P1"
+	}
+	return "P2"
+}
+
+// Opponent returns the other player
+func (p Perspective) Opponent() Perspective {
+	if p == Player1 {
+		return Player2
+	}
+	return Player1
+}
+
+// FrameSize returns the number of rows and columns of the grid recorded in a frame
+func FrameSize(p server.Packed) (rows, columns int) {
+	return (p.Y - 1) / cellSize, (p.X - 1) / cellSize
+}
+
+// StateFromPacked rebuilds the state of a frame of the history, seen by the given player
+func StateFromPacked(p server.Packed, persp Perspective) *model.State {
+	rows, columns := FrameSize(p)
+	s := model.NewState(uint8(rows), uint8(columns))
+
+	werewolves, vampires := model.Ally, model.Enemy
+	if persp == Player2 {
+		werewolves, vampires = vampires, werewolves
+	}
+
+	for _, c := range p.Humans {
+		// The server keeps humans groups that were wiped out during a lost battle, our client removes them
+		if c.Count > 0 {
+			s.SetCell(model.Coordinates{X: uint8(c.X / cellSize), Y: uint8(c.Y / cellSize)}, model.Neutral, uint8(c.Count))
+		}
+	}
+	for _, c := range p.Wolfs {
+		s.SetCell(model.Coordinates{X: uint8(c.X / cellSize), Y: uint8(c.Y / cellSize)}, werewolves, uint8(c.Count))
+	}
+	for _, c := range p.Vamps {
+		s.SetCell(model.Coordinates{X: uint8(c.X / cellSize), Y: uint8(c.Y / cellSize)}, vampires, uint8(c.Count))
+	}
+
+	return s
+}
+
+// State returns the state at the given index of the history, seen by the given player
+func (mr *MatchSummary) State(frame int, persp Perspective) (*model.State, error) {
+	if frame < 0 || frame >= len(mr.History) {
+		return nil, fmt.Errorf("frame %d out of range, replay has %d frames", frame, len(mr.History))
+	}
+
+	return StateFromPacked(mr.History[frame], persp), nil
+}
+
+// Participant returns the participant playing as the given player
+func (mr *MatchSummary) Participant(persp Perspective) Participant {
+	if persp == Player1 {
+		return mr.Player1
+	}
+	return mr.Player2
+}
+
+// Population returns the total number of humans, werewolves and vampires in a frame
+func Population(p server.Packed) (humans, werewolves, vampires int) {
+	for _, c := range p.Humans {
+		humans += c.Count
+	}
+	for _, c := range p.Wolfs {
+		werewolves += c.Count
+	}
+	for _, c := range p.Vamps {
+		vampires += c.Count
+	}
+	return humans, werewolves, vampires
+}
@@ -0,0 +1,638 @@
+package tournament
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/langorou/twilight/server"
+)
+
+// The compact replay format stores the metadata of a match followed by its history, gzipped:
+//
+//	magic "LGRP" | format version (1 byte) | header length (uvarint) | header (JSON) | gzip(body)
+//
+// The header is a ReplayHeader, it can be read without decoding the body. The body starts with the size of the grid
+// and the number of frames, then each frame is stored as the moves of the player who played, the results of the
+// battles they started and the cells that changed otherwise. The first frame is the initial map, stored as changes
+// to an empty grid:
+//
+//	rows | columns | frames | for each frame: turn | state |
+//		moves | for each move: x | y | count | x | y |
+//		battles | for each battle: x | y | kind | count |
+//		changes | for each change: x | y | kind | count
+//
+// A frame is rebuilt by moving the units from the previous one, setting the cells where a battle took place to its
+// result and applying the changes, which are only needed when legal moves don't explain a frame. Every number is an
+// uvarint and the state is an uvarint length followed by the bytes of the string.
+var replayMagic = []byte("LGRP")
+
+// ReplayFormatVersion is the version of the compact replay format
+const ReplayFormatVersion = 1
+
+// ReplayExt is the extension of the compact replay files
+const ReplayExt = ".lgr"
+
+// maxReplayFrames is far more frames than a game has, it bounds the memory used to decode a corrupt replay
+const maxReplayFrames = 1 << 16
+
+// Kinds of cells in the compact format, empty is used to remove a cell
+const (
+	kindEmpty = iota
+	kindHumans
+	kindWerewolves
+	kindVampires
+)
+
+// ReplayHeader holds the metadata of a match, without its history
+type ReplayHeader struct {
+	Version                int
+	MapName                string
+	Player1                Participant
+	Player2                Participant
+	Winner                 matchResult
+	Player1Eff, Player2Eff int
+	EndTurn                int
+	Seed                   int64
+	Duration               time.Duration
+	Frames                 int
+	ID                     string `json:",omitempty"`
+}
+
+// Header returns the metadata of the match
+func (mr *MatchSummary) Header() ReplayHeader {
+	return ReplayHeader{
+		Version:    mr.Version,
+		MapName:    mr.MapName,
+		Player1:    mr.Player1,
+		Player2:    mr.Player2,
+		Winner:     mr.Winner,
+		Player1Eff: mr.Player1Eff,
+		Player2Eff: mr.Player2Eff,
+		EndTurn:    mr.EndTurn,
+		Seed:       mr.Seed,
+		Duration:   mr.Duration,
+		Frames:     len(mr.History),
+		ID:         mr.ID,
+	}
+}
+
+// IsCompactReplay tells if data starts like a replay in the compact format
+func IsCompactReplay(data []byte) bool {
+	return bytes.HasPrefix(data, replayMagic)
+}
+
+type cellKey struct {
+	x, y int
+}
+
+type cellValue struct {
+	kind, count int
+}
+
+func frameGrid(p server.Packed) map[cellKey]cellValue {
+	grid := map[cellKey]cellValue{}
+	for _, c := range p.Humans {
+		grid[cellKey{c.X / cellSize, c.Y / cellSize}] = cellValue{kindHumans, c.Count}
+	}
+	for _, c := range p.Wolfs {
+		grid[cellKey{c.X / cellSize, c.Y / cellSize}] = cellValue{kindWerewolves, c.Count}
+	}
+	for _, c := range p.Vamps {
+		grid[cellKey{c.X / cellSize, c.Y / cellSize}] = cellValue{kindVampires, c.Count}
+	}
+	return grid
+}
+
+// replayMove is a move of a turn in the compact format
+type replayMove struct {
+	from, to cellKey
+	count    int
+}
+
+// replayCell is the content of a cell in the compact format, an empty kind removes the cell
+type replayCell struct {
+	key   cellKey
+	value cellValue
+}
+
+// replayTurn is a frame in the compact format
+type replayTurn struct {
+	moves   []replayMove
+	battles []replayCell
+	changes []replayCell
+}
+
+func lessCellKey(k1, k2 cellKey) bool {
+	return k1.y < k2.y || (k1.y == k2.y && k1.x < k2.x)
+}
+
+func adjacent(k1, k2 cellKey) bool {
+	dx, dy := k1.x-k2.x, k1.y-k2.y
+	return k1 != k2 && dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1
+}
+
+func sortedKeys(grid map[cellKey]cellValue) []cellKey {
+	keys := make([]cellKey, 0, len(grid))
+	for k := range grid {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessCellKey(keys[i], keys[j]) })
+	return keys
+}
+
+// inferTurn finds the moves of the units of the given kind between two grids and the results of the battles they
+// started. Like for analysis.InferCoup, the units sent into battles can only be deduced from the cells they left,
+// they are split evenly when several battles are in reach
+func inferTurn(previous, grid map[cellKey]cellValue, kind int) (moves []replayMove, battles []replayCell) {
+	left := map[cellKey]int{}
+	var sources []cellKey
+	for _, k := range sortedKeys(previous) {
+		before, after := previous[k], grid[k]
+		if before.kind != kind || (after.kind != kind && after.kind != kindEmpty) || after.count >= before.count {
+			continue
+		}
+		left[k] = before.count - after.count
+		sources = append(sources, k)
+	}
+
+	// The cells where units arrived without a battle get exactly what they gained, the other units attacked
+	var fights, arrivals []cellKey
+	for _, c := range diffGrids(previous, grid) {
+		if _, ok := left[c.key]; ok {
+			continue
+		}
+		before, after := previous[c.key], grid[c.key]
+		switch {
+		case before.kind != kindEmpty && before.kind != kind:
+			fights = append(fights, c.key)
+		case after.kind == kind && after.count > before.count:
+			arrivals = append(arrivals, c.key)
+		}
+	}
+
+	// Serve first the cells and use first the sources with the fewest options
+	reach := func(k cellKey, among []cellKey) int {
+		n := 0
+		for _, o := range among {
+			if adjacent(k, o) {
+				n++
+			}
+		}
+		return n
+	}
+	sort.SliceStable(arrivals, func(i, j int) bool { return reach(arrivals[i], sources) < reach(arrivals[j], sources) })
+	bySources := append([]cellKey{}, sources...)
+	sort.SliceStable(bySources, func(i, j int) bool { return reach(bySources[i], arrivals) < reach(bySources[j], arrivals) })
+
+	for _, k := range arrivals {
+		need := grid[k].count - previous[k].count
+		for _, src := range bySources {
+			if left[src] == 0 || !adjacent(src, k) {
+				continue
+			}
+			n := left[src]
+			if n > need {
+				n = need
+			}
+			moves = append(moves, replayMove{from: src, to: k, count: n})
+			left[src] -= n
+			need -= n
+			if need == 0 {
+				break
+			}
+		}
+	}
+
+	for _, src := range sources {
+		var inReach []cellKey
+		for _, k := range fights {
+			if adjacent(src, k) {
+				inReach = append(inReach, k)
+			}
+		}
+		for i, k := range inReach {
+			n := left[src] / (len(inReach) - i)
+			if n > 0 {
+				moves = append(moves, replayMove{from: src, to: k, count: n})
+				left[src] -= n
+			}
+		}
+	}
+	for _, k := range fights {
+		battles = append(battles, replayCell{key: k, value: grid[k]})
+	}
+	return moves, battles
+}
+
+// applyTurn moves the units of a turn on grid and sets the results of the battles, it returns an error if the moves
+// take units from a cell which doesn't have them
+func applyTurn(grid map[cellKey]cellValue, t replayTurn) error {
+	arrivals := map[cellKey]cellValue{}
+	for _, m := range t.moves {
+		src := grid[m.from]
+		if src.kind == kindEmpty || src.count < m.count || m.count == 0 {
+			return fmt.Errorf("can't move %d units from %+v which holds %d", m.count, m.from, src.count)
+		}
+		src.count -= m.count
+		if src.count == 0 {
+			delete(grid, m.from)
+		} else {
+			grid[m.from] = src
+		}
+		arrivals[m.to] = cellValue{kind: src.kind, count: arrivals[m.to].count + m.count}
+	}
+
+	fought := map[cellKey]bool{}
+	for _, b := range t.battles {
+		fought[b.key] = true
+	}
+	for k, v := range arrivals {
+		if !fought[k] {
+			grid[k] = cellValue{kind: v.kind, count: grid[k].count + v.count}
+		}
+	}
+
+	for _, cells := range [][]replayCell{t.battles, t.changes} {
+		for _, c := range cells {
+			if c.value.kind == kindEmpty {
+				delete(grid, c.key)
+			} else {
+				grid[c.key] = c.value
+			}
+		}
+	}
+	return nil
+}
+
+// diffGrids lists the cells to change for grid to become target
+func diffGrids(grid, target map[cellKey]cellValue) []replayCell {
+	var changes []replayCell
+	for k, v := range target {
+		if old, ok := grid[k]; !ok || old != v {
+			changes = append(changes, replayCell{key: k, value: v})
+		}
+	}
+	for k := range grid {
+		if _, ok := target[k]; !ok {
+			changes = append(changes, replayCell{key: k})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return lessCellKey(changes[i].key, changes[j].key) })
+	return changes
+}
+
+// replayTurns converts the history of a match to the turns of the compact format
+func replayTurns(mr *MatchSummary) []replayTurn {
+	turns := make([]replayTurn, 0, len(mr.History))
+	previous := map[cellKey]cellValue{}
+	for i, p := range mr.History {
+		grid := frameGrid(p)
+
+		var t replayTurn
+		if persp, ok := mr.Mover(i); ok {
+			kind := kindWerewolves
+			if persp == Player2 {
+				kind = kindVampires
+			}
+			t.moves, t.battles = inferTurn(previous, grid, kind)
+		}
+
+		rebuilt := map[cellKey]cellValue{}
+		for k, v := range previous {
+			rebuilt[k] = v
+		}
+		if applyTurn(rebuilt, t) != nil {
+			t = replayTurn{}
+			rebuilt = previous
+		}
+		t.changes = diffGrids(rebuilt, grid)
+
+		turns = append(turns, t)
+		previous = grid
+	}
+	return turns
+}
+
+type replayWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (rw *replayWriter) uvarint(v int) {
+	if rw.err != nil {
+		return
+	}
+	if v < 0 {
+		rw.err = fmt.Errorf("can't encode negative value %d", v)
+		return
+	}
+	n := binary.PutUvarint(rw.buf[:], uint64(v))
+	_, rw.err = rw.w.Write(rw.buf[:n])
+}
+
+func (rw *replayWriter) bytes(b []byte) {
+	rw.uvarint(len(b))
+	if rw.err == nil {
+		_, rw.err = rw.w.Write(b)
+	}
+}
+
+// EncodeReplay writes a match in the compact replay format
+func EncodeReplay(w io.Writer, mr *MatchSummary) error {
+	if len(mr.History) == 0 {
+		return fmt.Errorf("can't encode a match without history")
+	}
+
+	header, err := json.Marshal(mr.Header())
+	if err != nil {
+		return err
+	}
+
+	rw := &replayWriter{w: bufio.NewWriter(w)}
+	_, rw.err = rw.w.Write(replayMagic)
+	if rw.err == nil {
+		rw.err = rw.w.WriteByte(ReplayFormatVersion)
+	}
+	rw.bytes(header)
+	if rw.err != nil {
+		return rw.err
+	}
+
+	zw := gzip.NewWriter(rw.w)
+	body := &replayWriter{w: bufio.NewWriter(zw)}
+
+	rows, columns := FrameSize(mr.History[0])
+	body.uvarint(rows)
+	body.uvarint(columns)
+	body.uvarint(len(mr.History))
+
+	for i, t := range replayTurns(mr) {
+		body.uvarint(mr.History[i].Mov)
+		body.bytes([]byte(mr.History[i].State))
+		body.uvarint(len(t.moves))
+		for _, m := range t.moves {
+			body.uvarint(m.from.x)
+			body.uvarint(m.from.y)
+			body.uvarint(m.count)
+			body.uvarint(m.to.x)
+			body.uvarint(m.to.y)
+		}
+		for _, cells := range [][]replayCell{t.battles, t.changes} {
+			body.uvarint(len(cells))
+			for _, c := range cells {
+				body.uvarint(c.key.x)
+				body.uvarint(c.key.y)
+				body.uvarint(c.value.kind)
+				body.uvarint(c.value.count)
+			}
+		}
+	}
+
+	if body.err != nil {
+		return body.err
+	}
+	if err = body.w.Flush(); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	return rw.w.Flush()
+}
+
+// SaveReplay writes the match summary in the compact replay format
+func (mr *MatchSummary) SaveReplay(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = EncodeReplay(f, mr); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+type replayReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (rr *replayReader) uvarint() int {
+	if rr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		rr.err = err
+		return 0
+	}
+	if v > 1<<31 {
+		rr.err = fmt.Errorf("value %d is too big", v)
+		return 0
+	}
+	return int(v)
+}
+
+func (rr *replayReader) bytes(max int) []byte {
+	n := rr.uvarint()
+	if rr.err != nil {
+		return nil
+	}
+	if n > max {
+		rr.err = fmt.Errorf("length %d is too big", n)
+		return nil
+	}
+	b := make([]byte, n)
+	_, rr.err = io.ReadFull(rr.r, b)
+	return b
+}
+
+// readHeader reads the magic, the format version and the header
+func readHeader(rr *replayReader) (*ReplayHeader, error) {
+	magic := make([]byte, len(replayMagic)+1)
+	if _, err := io.ReadFull(rr.r, magic); err != nil {
+		return nil, corrupt(-1, "can't read the format: %s", err)
+	}
+	if !bytes.Equal(magic[:len(replayMagic)], replayMagic) {
+		return nil, corrupt(-1, "not a compact replay")
+	}
+	if version := magic[len(replayMagic)]; version != ReplayFormatVersion {
+		return nil, corrupt(-1, "unsupported replay format version %d, latest supported is %d", version, ReplayFormatVersion)
+	}
+
+	data := rr.bytes(1 << 20)
+	if rr.err != nil {
+		return nil, corrupt(-1, "can't read the header: %s", rr.err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var header ReplayHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, corrupt(-1, "invalid header: %s", err)
+	}
+	return &header, nil
+}
+
+// ReadReplayHeader reads the metadata of a match in the compact replay format, without decoding its history
+func ReadReplayHeader(r io.Reader) (*ReplayHeader, error) {
+	return readHeader(&replayReader{r: bufio.NewReader(r)})
+}
+
+// packedJSON mirrors server.Packed, whose cells can't be built outside of the server package but can be decoded
+type packedJSON struct {
+	X, Y   int
+	Humans []cellJSON
+	Vamps  []cellJSON
+	Wolfs  []cellJSON
+	State  string
+	Mov    int
+}
+
+type cellJSON struct {
+	Count int `json:"c"`
+	X     int
+	Y     int
+}
+
+// toPacked rebuilds a frame, cells are ordered like the server does: by column then row
+func toPacked(rows, columns, mov int, state string, grid map[cellKey]cellValue) (server.Packed, error) {
+	p := packedJSON{
+		X:      columns*cellSize + 1,
+		Y:      rows*cellSize + 1,
+		Humans: []cellJSON{},
+		Vamps:  []cellJSON{},
+		Wolfs:  []cellJSON{},
+		State:  state,
+		Mov:    mov,
+	}
+
+	keys := make([]cellKey, 0, len(grid))
+	for k := range grid {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].x < keys[j].x || (keys[i].x == keys[j].x && keys[i].y < keys[j].y)
+	})
+
+	for _, k := range keys {
+		c := cellJSON{Count: grid[k].count, X: k.x * cellSize, Y: k.y * cellSize}
+		switch grid[k].kind {
+		case kindHumans:
+			p.Humans = append(p.Humans, c)
+		case kindWerewolves:
+			p.Wolfs = append(p.Wolfs, c)
+		case kindVampires:
+			p.Vamps = append(p.Vamps, c)
+		}
+	}
+
+	var packed server.Packed
+	data, err := json.Marshal(p)
+	if err == nil {
+		err = json.Unmarshal(data, &packed)
+	}
+	return packed, err
+}
+
+// readCells reads a list of cells
+func readCells(body *replayReader) ([]replayCell, error) {
+	n := body.uvarint()
+	var cells []replayCell
+	for j := 0; j < n && body.err == nil; j++ {
+		c := replayCell{key: cellKey{body.uvarint(), body.uvarint()}, value: cellValue{body.uvarint(), body.uvarint()}}
+		if c.value.kind > kindVampires {
+			return nil, fmt.Errorf("invalid cell kind %d", c.value.kind)
+		}
+		cells = append(cells, c)
+	}
+	return cells, nil
+}
+
+// DecodeReplay reads a match in the compact replay format, it doesn't validate the match (see DecodeMatchSummary)
+func DecodeReplay(r io.Reader) (*MatchSummary, error) {
+	rr := &replayReader{r: bufio.NewReader(r)}
+	header, err := readHeader(rr)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(rr.r)
+	if err != nil {
+		return nil, corrupt(-1, "invalid body: %s", err)
+	}
+	defer zr.Close()
+	body := &replayReader{r: bufio.NewReader(zr)}
+
+	rows, columns, frames := body.uvarint(), body.uvarint(), body.uvarint()
+	if body.err != nil {
+		return nil, corrupt(-1, "can't read the body: %s", body.err)
+	}
+	if rows > math.MaxUint8 || columns > math.MaxUint8 {
+		return nil, corrupt(-1, "grid of %dx%d cells is too big", rows, columns)
+	}
+	if frames > maxReplayFrames {
+		return nil, corrupt(-1, "%d frames are too many", frames)
+	}
+	if frames != header.Frames {
+		return nil, corrupt(-1, "header announces %d frames but the body has %d", header.Frames, frames)
+	}
+
+	mr := &MatchSummary{
+		Version:    header.Version,
+		MapName:    header.MapName,
+		Player1:    header.Player1,
+		Player2:    header.Player2,
+		Winner:     header.Winner,
+		Player1Eff: header.Player1Eff,
+		Player2Eff: header.Player2Eff,
+		EndTurn:    header.EndTurn,
+		Seed:       header.Seed,
+		Duration:   header.Duration,
+		ID:         header.ID,
+	}
+
+	grid := map[cellKey]cellValue{}
+	for i := 0; i < frames; i++ {
+		mov := body.uvarint()
+		state := body.bytes(64)
+
+		var t replayTurn
+		n := body.uvarint()
+		for j := 0; j < n && body.err == nil; j++ {
+			t.moves = append(t.moves, replayMove{from: cellKey{body.uvarint(), body.uvarint()}, count: body.uvarint(), to: cellKey{body.uvarint(), body.uvarint()}})
+		}
+		if t.battles, err = readCells(body); err != nil {
+			return nil, corrupt(i, "%s", err)
+		}
+		if t.changes, err = readCells(body); err != nil {
+			return nil, corrupt(i, "%s", err)
+		}
+		if body.err != nil {
+			return nil, corrupt(i, "truncated frame: %s", body.err)
+		}
+		if err = applyTurn(grid, t); err != nil {
+			return nil, corrupt(i, "%s", err)
+		}
+
+		p, err := toPacked(rows, columns, mov, string(state), grid)
+		if err != nil {
+			return nil, corrupt(i, "%s", err)
+		}
+		mr.History = append(mr.History, p)
+	}
+
+	return mr, nil
+}
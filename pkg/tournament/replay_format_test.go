@@ -0,0 +1,172 @@
+package tournament
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTestReplay(t *testing.T) (*MatchSummary, []byte) {
+	mr, err := LoadMatchSummary(testReplayPath)
+	require.NoError(t, err)
+	mr.Seed = 42
+	mr.ID = "dumb_VS_dumbONthetrap_1"
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeReplay(&buf, mr))
+	return mr, buf.Bytes()
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	mr, data := encodeTestReplay(t)
+
+	original, err := ioutil.ReadFile(testReplayPath)
+	require.NoError(t, err)
+	assert.True(t, len(data) < len(original)/4, "compact replay is %d bytes, JSON is %d bytes", len(data), len(original))
+	assert.True(t, IsCompactReplay(data))
+
+	decoded, err := DecodeMatchSummary(data)
+	require.NoError(t, err)
+
+	// Compare the JSON documents so that the cells of the frames are compared too
+	expected, err := json.Marshal(mr)
+	require.NoError(t, err)
+	actual, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestReplayTurns(t *testing.T) {
+	mr, err := LoadMatchSummary(testReplayPath)
+	require.NoError(t, err)
+	turns := replayTurns(mr)
+	require.Len(t, turns, len(mr.History))
+
+	// The initial map, then only moves and battles
+	assert.Empty(t, turns[0].moves)
+	assert.Len(t, turns[0].changes, 6)
+	for i, turn := range turns[1:] {
+		assert.NotEmpty(t, turn.moves, "frame %d", i+1)
+		assert.Empty(t, turn.changes, "frame %d", i+1)
+	}
+
+	// The werewolves split
+	assert.Equal(t, []replayMove{{from: cellKey{5, 0}, to: cellKey{6, 1}, count: 2}, {from: cellKey{4, 2}, to: cellKey{4, 1}, count: 2}}, turns[7].moves)
+	// The vampires take the humans
+	assert.Equal(t, []replayMove{{from: cellKey{3, 2}, to: cellKey{2, 2}, count: 4}}, turns[4].moves)
+	assert.Equal(t, []replayCell{{key: cellKey{2, 2}, value: cellValue{kindVampires, 8}}}, turns[4].battles)
+}
+
+func TestReplayBattles(t *testing.T) {
+	// A repelled attack, a frame where nothing happens and one that no move explains
+	var mr MatchSummary
+	require.NoError(t, json.Unmarshal([]byte(`{"History": [
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 5, "X": 0, "Y": 0}], "Vamps": [{"c": 4, "X": 80, "Y": 160}], "State": "", "Mov": 0},
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 5, "X": 0, "Y": 80}], "Vamps": [{"c": 4, "X": 80, "Y": 160}], "State": "", "Mov": 1},
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 3, "X": 0, "Y": 80}], "Vamps": [{"c": 1, "X": 80, "Y": 160}], "State": "", "Mov": 2},
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 3, "X": 0, "Y": 80}], "Vamps": [{"c": 1, "X": 80, "Y": 160}], "State": "", "Mov": 2},
+		{"X": 241, "Y": 241, "Humans": [], "Wolfs": [{"c": 3, "X": 160, "Y": 0}], "Vamps": [{"c": 1, "X": 80, "Y": 160}], "State": "", "Mov": 3}
+	]}`), &mr))
+
+	turns := replayTurns(&mr)
+	assert.Equal(t, []replayMove{{from: cellKey{1, 2}, to: cellKey{0, 1}, count: 3}}, turns[2].moves)
+	assert.Equal(t, []replayCell{{key: cellKey{0, 1}, value: cellValue{kindWerewolves, 3}}}, turns[2].battles)
+	assert.Equal(t, replayTurn{}, turns[3])
+	assert.Empty(t, turns[4].moves)
+	assert.Len(t, turns[4].changes, 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeReplay(&buf, &mr))
+	decoded, err := DecodeReplay(&buf)
+	require.NoError(t, err)
+	expected, err := json.Marshal(mr.History)
+	require.NoError(t, err)
+	actual, err := json.Marshal(decoded.History)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// Units can't come out of nowhere
+	grid := map[cellKey]cellValue{{0, 0}: {kindWerewolves, 2}}
+	assert.Error(t, applyTurn(grid, replayTurn{moves: []replayMove{{from: cellKey{0, 0}, to: cellKey{1, 0}, count: 3}}}))
+	assert.Error(t, applyTurn(grid, replayTurn{moves: []replayMove{{from: cellKey{1, 1}, to: cellKey{1, 0}, count: 1}}}))
+}
+
+func TestReadReplayHeader(t *testing.T) {
+	mr, data := encodeTestReplay(t)
+
+	// The header doesn't need the body
+	header, err := ReadReplayHeader(bytes.NewReader(data[:len(data)-20]))
+	require.NoError(t, err)
+	assert.Equal(t, mr.Header(), *header)
+	assert.Equal(t, int64(42), header.Seed)
+	assert.Equal(t, 15, header.Frames)
+	assert.Equal(t, player2Won, header.Winner)
+	assert.Equal(t, "dumb_VS_dumbONthetrap_1", header.ID)
+}
+
+func TestDecodeReplayErrors(t *testing.T) {
+	_, data := encodeTestReplay(t)
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := DecodeMatchSummary(data[:len(data)-20])
+		require.Error(t, err)
+		assert.IsType(t, &CorruptReplayError{}, err)
+	})
+
+	t.Run("future format", func(t *testing.T) {
+		future := append([]byte{}, data...)
+		future[len(replayMagic)] = ReplayFormatVersion + 1
+		_, err := DecodeMatchSummary(future)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported replay format version")
+	})
+
+	// A few bytes must not make the decoder allocate for every frame or cell they announce
+	crafted := func(rows, columns, frames uint64) []byte {
+		var body bytes.Buffer
+		zw := gzip.NewWriter(&body)
+		for _, v := range []uint64{rows, columns, frames} {
+			zw.Write(varint(v))
+		}
+		require.NoError(t, zw.Close())
+
+		header := []byte(fmt.Sprintf(`{"Frames": %d}`, frames))
+		data := append(append([]byte{}, replayMagic...), ReplayFormatVersion)
+		data = append(data, varint(uint64(len(header)))...)
+		return append(append(data, header...), body.Bytes()...)
+	}
+
+	t.Run("too many frames", func(t *testing.T) {
+		_, err := DecodeMatchSummary(crafted(10, 10, 1<<30))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "too many")
+	})
+
+	t.Run("too big grid", func(t *testing.T) {
+		_, err := DecodeMatchSummary(crafted(1<<20, 10, 1))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "too big")
+	})
+
+	t.Run("missing frames", func(t *testing.T) {
+		_, err := DecodeMatchSummary(crafted(10, 10, maxReplayFrames))
+		require.Error(t, err)
+		assert.IsType(t, &CorruptReplayError{}, err)
+	})
+
+	t.Run("empty history", func(t *testing.T) {
+		assert.Error(t, EncodeReplay(&bytes.Buffer{}, &MatchSummary{}))
+	})
+}
+
+func varint(v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, v)]
+}
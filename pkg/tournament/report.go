@@ -0,0 +1,284 @@
+package tournament
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Race played by each player, the first player is always werewolves
+const (
+	player1Race = "werewolves"
+	player2Race = "vampires"
+)
+
+// Points given for a win, a draw and a loss
+const (
+	winPoints  = 3
+	drawPoints = 1
+	lossPoints = 0
+)
+
+// Record counts the outcomes of a set of matches
+type Record struct {
+	Wins, Draws, Losses int
+}
+
+// Points returns the points earned with this record
+func (r Record) Points() int {
+	return r.Wins*winPoints + r.Draws*drawPoints + r.Losses*lossPoints
+}
+
+// Matches returns the number of matches of this record
+func (r Record) Matches() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+func (r *Record) add(winner matchResult, side matchResult) {
+	switch winner {
+	case tie:
+		r.Draws++
+	case side:
+		r.Wins++
+	default:
+		r.Losses++
+	}
+}
+
+// Standing is the position of a participant in the leaderboard
+type Standing struct {
+	Name string
+	Record
+	Points   int
+	AvgTurns float64
+}
+
+// MatchReport is the summary of a match, without its history
+type MatchReport struct {
+	Map           string
+	Player1       string
+	Player1Params string
+	Player1Race   string
+	Player2       string
+	Player2Params string
+	Player2Race   string
+	Player1Eff    int
+	Player2Eff    int
+	// Winner is the name of the winner, empty for a draw
+	Winner   string
+	Turns    int
+	Duration float64
+	// Replay is the path of the replay, relative to the report
+	Replay string
+}
+
+// Report is a structured summary of a tournament
+type Report struct {
+	Standings []Standing
+	// HeadToHead gives the record of a participant against each of its opponents
+	HeadToHead map[string]map[string]Record
+	Matches    []MatchReport
+}
+
+func participantParams(p Participant) string {
+	if p.Spec != "" {
+		return p.Spec
+	}
+	if p.Dumb {
+		return "dumb"
+	}
+	return p.Params.ShortString()
+}
+
+// Standings returns the leaderboard sorted by points, wins, then names
+func (tr Result) Standings() []Standing {
+	records := map[string]*Record{}
+	turns := map[string]int{}
+
+	for _, mr := range tr {
+		for _, side := range []struct {
+			name   string
+			result matchResult
+		}{{mr.Player1.Name(), player1Won}, {mr.Player2.Name(), player2Won}} {
+			r, ok := records[side.name]
+			if !ok {
+				r = &Record{}
+				records[side.name] = r
+			}
+			r.add(mr.Winner, side.result)
+			turns[side.name] += mr.EndTurn
+		}
+	}
+
+	standings := make([]Standing, 0, len(records))
+	for name, r := range records {
+		standings = append(standings, Standing{
+			Name:     name,
+			Record:   *r,
+			Points:   r.Points(),
+			AvgTurns: float64(turns[name]) / float64(r.Matches()),
+		})
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Name < b.Name
+	})
+
+	return standings
+}
+
+// Report builds the structured summary of the tournament, replayDir is the path of the replays relative to the report
+func (tr Result) Report(replayDir string) *Report {
+	report := &Report{
+		Standings:  tr.Standings(),
+		HeadToHead: map[string]map[string]Record{},
+	}
+
+	addHeadToHead := func(name, opponent string, winner, side matchResult) {
+		if _, ok := report.HeadToHead[name]; !ok {
+			report.HeadToHead[name] = map[string]Record{}
+		}
+		r := report.HeadToHead[name][opponent]
+		r.add(winner, side)
+		report.HeadToHead[name][opponent] = r
+	}
+
+	for _, mr := range tr {
+		p1, p2 := mr.Player1.Name(), mr.Player2.Name()
+		addHeadToHead(p1, p2, mr.Winner, player1Won)
+		addHeadToHead(p2, p1, mr.Winner, player2Won)
+
+		m := MatchReport{
+			Map:           mr.MapName,
+			Player1:       p1,
+			Player1Params: participantParams(mr.Player1),
+			Player1Race:   player1Race,
+			Player2:       p2,
+			Player2Params: participantParams(mr.Player2),
+			Player2Race:   player2Race,
+			Player1Eff:    mr.Player1Eff,
+			Player2Eff:    mr.Player2Eff,
+			Turns:         mr.EndTurn,
+			Duration:      mr.Duration.Seconds(),
+			Replay:        path.Join(replayDir, mr.replayName()),
+		}
+		switch mr.Winner {
+		case player1Won:
+			m.Winner = p1
+		case player2Won:
+			m.Winner = p2
+		}
+		report.Matches = append(report.Matches, m)
+	}
+
+	return report
+}
+
+// WriteCSV writes one line per match
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"map",
+		"player1", "player1_params", "player1_race",
+		"player2", "player2_params", "player2_race",
+		"player1_eff", "player2_eff", "winner", "turns", "duration_s", "replay",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range r.Matches {
+		err = cw.Write([]string{
+			m.Map,
+			m.Player1, m.Player1Params, m.Player1Race,
+			m.Player2, m.Player2Params, m.Player2Race,
+			strconv.Itoa(m.Player1Eff), strconv.Itoa(m.Player2Eff), m.Winner, strconv.Itoa(m.Turns),
+			strconv.FormatFloat(m.Duration, 'f', 3, 64), m.Replay,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+	"record": func(r *Report, name, opponent string) string {
+		rec, ok := r.HeadToHead[name][opponent]
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("%d-%d-%d", rec.Wins, rec.Draws, rec.Losses)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Tournament report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: right; }
+th { background: #eee; }
+td.name { text-align: left; }
+.werewolves { color: #c0272d; }
+.vampires { color: #234ea8; }
+</style>
+</head>
+<body>
+<h1>Tournament report</h1>
+
+<h2>Leaderboard</h2>
+<table>
+<tr><th>#</th><th>Participant</th><th>Points</th><th>Wins</th><th>Draws</th><th>Losses</th><th>Avg turns</th></tr>
+{{- range $i, $s := .Standings}}
+<tr><td>{{inc $i}}</td><td class="name">{{$s.Name}}</td><td>{{$s.Points}}</td><td>{{$s.Wins}}</td><td>{{$s.Draws}}</td><td>{{$s.Losses}}</td><td>{{printf "%.1f" $s.AvgTurns}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Head to head</h2>
+<p>Wins-draws-losses of the participant of the row against the participant of the column.</p>
+<table>
+<tr><th></th>{{range $i, $s := .Standings}}<th title="{{$s.Name}}">{{inc $i}}</th>{{end}}</tr>
+{{- range $i, $row := .Standings}}
+<tr><th title="{{$row.Name}}">{{inc $i}}</th>{{range $col := $.Standings}}<td>{{record $ $row.Name $col.Name}}</td>{{end}}</tr>
+{{- end}}
+</table>
+
+<h2>Matches</h2>
+<table>
+<tr><th>Map</th><th>Player 1</th><th>Player 2</th><th>Score</th><th>Winner</th><th>Turns</th><th>Duration (s)</th><th>Replay</th></tr>
+{{- range .Matches}}
+<tr><td class="name">{{.Map}}</td><td class="name werewolves">{{.Player1}}</td><td class="name vampires">{{.Player2}}</td><td>{{.Player1Eff}} - {{.Player2Eff}}</td><td class="name">{{if .Winner}}{{.Winner}}{{else}}draw{{end}}</td><td>{{.Turns}}</td><td>{{printf "%.1f" .Duration}}</td><td><a href="{{.Replay}}">replay</a></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a self-contained HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
@@ -0,0 +1,90 @@
+package tournament
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testResult() Result {
+	dumb := Participant{Dumb: true}
+	minMax := Participant{Timeout: time.Second, Params: client.NewDefaultHeuristicParameters()}
+
+	return Result{
+		{MapName: "maps/a.xml", Player1: dumb, Player2: minMax, Winner: player2Won, Player1Eff: 0, Player2Eff: 12, EndTurn: 20, Duration: 3 * time.Second},
+		{MapName: "maps/a.xml", Player1: minMax, Player2: dumb, Winner: player1Won, Player1Eff: 15, Player2Eff: 0, EndTurn: 10, Duration: 2 * time.Second},
+		{MapName: "maps/b.xml", Player1: dumb, Player2: minMax, Winner: tie, Player1Eff: 4, Player2Eff: 4, EndTurn: 30, Duration: time.Second},
+	}
+}
+
+func TestStandings(t *testing.T) {
+	tr := testResult()
+	standings := tr.Standings()
+	require.Len(t, standings, 2)
+
+	minMax, dumb := standings[0], standings[1]
+	assert.Equal(t, tr[0].Player2.Name(), minMax.Name)
+	assert.Equal(t, Record{Wins: 2, Draws: 1}, minMax.Record)
+	assert.Equal(t, 7, minMax.Points)
+	assert.Equal(t, 20., minMax.AvgTurns)
+
+	assert.Equal(t, "dumb IA", dumb.Name)
+	assert.Equal(t, Record{Draws: 1, Losses: 2}, dumb.Record)
+	assert.Equal(t, 1, dumb.Points)
+
+	assert.Regexp(t, `^\s*min_max.* -   7 points\n\s*dumb IA -   1 points\n$`, tr.Leaderboard())
+}
+
+func TestReport(t *testing.T) {
+	tr := testResult()
+	report := tr.Report("1_matches")
+	minMax := tr[0].Player2.Name()
+
+	assert.Equal(t, Record{Wins: 2, Draws: 1}, report.HeadToHead[minMax]["dumb IA"])
+	assert.Equal(t, Record{Draws: 1, Losses: 2}, report.HeadToHead["dumb IA"][minMax])
+
+	require.Len(t, report.Matches, 3)
+	assert.Equal(t, minMax, report.Matches[0].Winner)
+	assert.Equal(t, "", report.Matches[2].Winner)
+	assert.Equal(t, "dumb", report.Matches[0].Player1Params)
+	assert.Equal(t, "werewolves", report.Matches[0].Player1Race)
+	assert.Equal(t, "1_matches/"+tr[0].shortName()+ReplayExt, report.Matches[0].Replay)
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteCSV(&buf))
+
+		lines, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, lines, 4)
+		assert.Equal(t, "map", lines[0][0])
+		assert.Equal(t, []string{"maps/a.xml", "dumb IA", "dumb", "werewolves"}, lines[1][:4])
+		assert.Equal(t, []string{"0", "12", minMax, "20", "3.000"}, lines[1][7:12])
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteJSON(&buf))
+
+		var decoded Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, *report, decoded)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteHTML(&buf))
+
+		html := buf.String()
+		assert.Contains(t, html, `<td>2-1-0</td>`)
+		assert.Contains(t, html, `<td>0-1-2</td>`)
+		assert.Contains(t, html, `<a href="1_matches/`)
+		assert.Contains(t, html, `>draw</td>`)
+	})
+}
@@ -3,16 +3,20 @@ package tournament
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/langorou/langorou/pkg/client"
+	"github.com/langorou/langorou/pkg/logging"
 	"github.com/langorou/langorou/pkg/utils"
 	"github.com/langorou/twilight/server"
 )
@@ -35,20 +39,31 @@ func newRandomMap(limits RandMapLimits) mapParams {
 }
 
 type Participant struct {
+	// Spec of the IA as accepted by client.NewIA, the other fields are ignored when it is set
+	Spec    string `json:",omitempty"`
 	Dumb    bool
 	Timeout time.Duration
 	Params  client.HeuristicParameters
 }
 
-func (p Participant) createPlayer() client.IA {
+// specReplacer makes the names of the spec participants usable in file names
+var specReplacer = strings.NewReplacer(":", "_", ",", "_", "/", "_", "=", "-")
+
+func (p Participant) createPlayer() (client.IA, error) {
+	if p.Spec != "" {
+		return client.NewIA(p.Spec)
+	}
 	if p.Dumb {
-		return client.NewDumbIA()
+		return client.NewDumbIA(), nil
 	}
 
-	return client.NewMinMaxIAP(p.Timeout, p.Params)
+	return client.NewMinMaxIAP(p.Timeout, p.Params), nil
 }
 
 func (p Participant) Name() string {
+	if p.Spec != "" {
+		return specReplacer.Replace(p.Spec)
+	}
 	if p.Dumb {
 		return "dumb IA"
 	}
@@ -65,13 +80,20 @@ const (
 )
 
 type MatchSummary struct {
+	Version                int
 	MapName                string
 	Player1                Participant
 	Player2                Participant
 	Winner                 matchResult
 	Player1Eff, Player2Eff int
 	EndTurn                int
-	History                []server.Packed
+	// Seed of the random generator of the tournament, 0 if unknown
+	Seed int64
+	// Duration of the match, 0 if unknown
+	Duration time.Duration
+	History  []server.Packed
+	// ID identifies the match in the tournament, it is assigned when the match is queued and empty if unknown
+	ID string
 }
 
 func (mr *MatchSummary) String() string {
@@ -103,7 +125,25 @@ func (mr *MatchSummary) shortName() string {
 
 }
 
-func (mr *MatchSummary) saveJSON(path string) error {
+// replayName is the name of the replay file of the match, the ID is preferred since random maps with the same
+// parameters give the same short name
+func (mr *MatchSummary) replayName() string {
+	if mr.ID != "" {
+		return mr.ID + ReplayExt
+	}
+	return mr.shortName() + ReplayExt
+}
+
+// matchCount counts the matches queued by all the tournaments, it makes the match IDs unique
+var matchCount uint64
+
+// newMatchID returns a unique ID for a match named name
+func newMatchID(name string) string {
+	return fmt.Sprintf("%s_%d", name, atomic.AddUint64(&matchCount, 1))
+}
+
+// SaveJSON writes the match summary as JSON
+func (mr *MatchSummary) SaveJSON(path string) error {
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -134,31 +174,9 @@ func (tr Result) Leaderboard() string {
 	// gagnant 3 points
 	// perdant 0 points
 	// égalité 1 point chacun
-	leaderboard := make(map[string]int)
-
-	for _, mr := range tr {
-		switch mr.Winner {
-		case tie:
-			leaderboard[mr.Player1.Name()]++
-			leaderboard[mr.Player2.Name()]++
-		case player1Won:
-			leaderboard[mr.Player1.Name()] += 3
-			if _, ok := leaderboard[mr.Player2.Name()]; !ok {
-				leaderboard[mr.Player2.Name()] = 0 // We might never add points to the looser
-			}
-		case player2Won:
-			leaderboard[mr.Player2.Name()] += 3
-			if _, ok := leaderboard[mr.Player1.Name()]; !ok {
-				leaderboard[mr.Player1.Name()] = 0 // We might never add points to the looser
-			}
-		}
-
-	}
-
 	var output string
-	for name, score := range leaderboard {
-		// we'll not be sorted (random map)
-		output += fmt.Sprintf("%15s - %3d points\n", name, score)
+	for _, s := range tr.Standings() {
+		output += fmt.Sprintf("%15s - %3d points\n", s.Name, s.Points)
 	}
 
 	return output
@@ -181,14 +199,25 @@ func (tr Result) Save(path string) error {
 	f.Sync()
 	f.Close()
 
-	dirPath := filepath.Join(path, fmt.Sprintf("%s_matches", t))
+	dirName := fmt.Sprintf("%s_matches", t)
+	dirPath := filepath.Join(path, dirName)
 	if err = utils.CreateDirIfNotExist(dirPath); err != nil {
 		return err
 	}
 
+	report := tr.Report(dirName)
+	for ext, write := range map[string]func(io.Writer) error{
+		"csv":  report.WriteCSV,
+		"json": report.WriteJSON,
+		"html": report.WriteHTML,
+	} {
+		if err = writeFile(filepath.Join(path, fmt.Sprintf("%s_tournament.%s", t, ext)), write); err != nil {
+			return err
+		}
+	}
+
 	for _, mr := range tr {
-		filename := fmt.Sprintf("%s.json", mr.shortName())
-		if err = mr.saveJSON(filepath.Join(dirPath, filename)); err != nil {
+		if err = mr.SaveReplay(filepath.Join(dirPath, mr.replayName())); err != nil {
 			return err
 		}
 	}
@@ -196,11 +225,25 @@ func (tr Result) Save(path string) error {
 	return nil
 }
 
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = write(f); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
 type job interface {
 	execute() error
 }
 
 type playMap struct {
+	id             string
 	mapPath        string
 	isRand         bool
 	randMapParams  mapParams
@@ -208,13 +251,43 @@ type playMap struct {
 	p1             Participant
 	p2             Participant
 	matchSummaryCh chan MatchSummary
+	observer       MatchObserver
 	wg             *sync.WaitGroup
 }
 
+func (pm playMap) mapName() string {
+	if pm.isRand {
+		return pm.randMapParams.String()
+	}
+	return pm.mapPath
+}
+
 func (pm playMap) execute() error {
 
 	defer pm.wg.Done()
 
+	if pm.observer != nil {
+		pm.observer.MatchStarted(pm.id)
+	}
+	err := pm.play()
+	if err != nil && pm.observer != nil {
+		pm.observer.MatchFailed(pm.id, err)
+	}
+	return err
+}
+
+func (pm playMap) play() error {
+
+	// Invalid specs are reported before starting a server that nobody would join
+	ia1, err := pm.p1.createPlayer()
+	if err != nil {
+		return err
+	}
+	ia2, err := pm.p2.createPlayer()
+	if err != nil {
+		return err
+	}
+
 	portUsed := make(chan int, 1)
 	gameOutcomeCh := make(chan server.GameOutcome, 1)
 
@@ -238,40 +311,49 @@ func (pm playMap) execute() error {
 
 	log.Printf("Launching %s vs %s on %s", pm.p1.Name(), pm.p2.Name(), addr)
 
-	player1, err := client.NewTCPClient(addr, pm.p1.Name(), pm.p1.createPlayer())
+	// The logs of the concurrent matches are told apart with the match field
+	logger := logging.Default().With("match", pm.id)
+
+	player1, err := client.NewTCPClient(addr, pm.p1.Name(), ia1)
 	if err != nil {
 		return err
 	}
+	player1.SetLogger(logger)
 	if err = player1.Init(); err != nil {
 		return fmt.Errorf("fail to init player 1: %s", err)
 	}
 
-	player2, err := client.NewTCPClient(addr, pm.p2.Name(), pm.p2.createPlayer())
+	player2, err := client.NewTCPClient(addr, pm.p2.Name(), ia2)
 	if err != nil {
 		return err
 	}
+	player2.SetLogger(logger)
 	if err = player2.Init(); err != nil {
 		return fmt.Errorf("fail to init player 2: %s", err)
 	}
 
+	// Never lose a match because an IA overran the timeout of the server
+	deadline := client.MoveDeadline{Deadline: time.Duration(pm.timeoutS) * time.Second, Margin: client.DefaultMoveMargin}
+	player1.SetMoveDeadline(deadline)
+	player2.SetMoveDeadline(deadline)
+
+	start := time.Now()
 	go player1.Play()
 	player2.Play()
 
 	outcome := <-gameOutcomeCh
 
 	matchRes := MatchSummary{
+		Version:    MatchSummaryVersion,
 		EndTurn:    outcome.Turn,
 		History:    outcome.History,
 		Player1:    pm.p1,
 		Player2:    pm.p2,
 		Player1Eff: outcome.P1Eff,
 		Player2Eff: outcome.P2Eff,
-	}
-
-	if pm.isRand {
-		matchRes.MapName = pm.randMapParams.String()
-	} else {
-		matchRes.MapName = pm.mapPath
+		Duration:   time.Since(start),
+		MapName:    pm.mapName(),
+		ID:         pm.id,
 	}
 
 	switch {
@@ -304,6 +386,7 @@ func RunTournamentOnMap(
 	timeoutS int,
 	competitors []Participant,
 	matchSummaryCh chan MatchSummary,
+	observer MatchObserver,
 ) {
 
 	var wg sync.WaitGroup
@@ -326,11 +409,13 @@ func RunTournamentOnMap(
 		}(i)
 	}
 
+	// Every match is reported as queued before any is sent to the workers, sending blocks while they are busy
+	var matches []playMap
 	for i, p1 := range competitors {
 		for j, p2 := range competitors {
 			if i != j {
-				wg.Add(1)
-				concurrentPlays <- playMap{
+				pm := playMap{
+					"",
 					mapPath,
 					isRand,
 					randMapParams,
@@ -338,12 +423,23 @@ func RunTournamentOnMap(
 					p1,
 					p2,
 					matchSummaryCh,
+					observer,
 					&wg,
 				}
+				pm.id = newMatchID((&MatchSummary{MapName: pm.mapName(), Player1: p1, Player2: p2}).shortName())
+				if observer != nil {
+					observer.MatchQueued(pm.id, p1, p2, pm.mapName())
+				}
+				matches = append(matches, pm)
 			}
 		}
 	}
 
+	wg.Add(len(matches))
+	for _, pm := range matches {
+		concurrentPlays <- pm
+	}
+
 	wg.Wait()
 	close(concurrentPlays)
 
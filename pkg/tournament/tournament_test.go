@@ -0,0 +1,68 @@
+package tournament
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/langorou/langorou/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecParticipant(t *testing.T) {
+	p := Participant{Spec: "minmax:timeout=200ms,battles=0.5"}
+	assert.Equal(t, "minmax_timeout-200ms_battles-0.5", p.Name())
+	assert.Equal(t, "minmax:timeout=200ms,battles=0.5", participantParams(p))
+
+	ia, err := p.createPlayer()
+	require.NoError(t, err)
+	require.IsType(t, &client.MinMaxIA{}, ia)
+	assert.Equal(t, 200*time.Millisecond, ia.(*client.MinMaxIA).Timeout())
+
+	_, err = Participant{Spec: "minmax:depth=3"}.createPlayer()
+	assert.Error(t, err)
+}
+
+// recordingObserver records the events of a tournament in order
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+	ids    map[string]bool
+}
+
+func (o *recordingObserver) record(event, id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+	o.ids[id] = true
+}
+
+func (o *recordingObserver) MatchQueued(id string, p1, p2 Participant, mapName string) {
+	o.record("queued", id)
+}
+func (o *recordingObserver) MatchStarted(id string)           { o.record("started", id) }
+func (o *recordingObserver) MatchFailed(id string, err error) { o.record("failed", id) }
+
+func TestRunTournamentOnMapObserver(t *testing.T) {
+	// Invalid specs make the matches fail before starting a server
+	competitors := []Participant{{Spec: "minmax:depth=1"}, {Spec: "minmax:depth=2"}, {Spec: "minmax:depth=3"}}
+	o := &recordingObserver{ids: map[string]bool{}}
+
+	RunTournamentOnMap("maps/same.xml", false, RandMapLimits{}, 1, competitors, make(chan MatchSummary), o)
+	RunTournamentOnMap("maps/same.xml", false, RandMapLimits{}, 1, competitors, make(chan MatchSummary), o)
+
+	// Every match of a tournament is queued before the first one starts
+	require.Len(t, o.events, 36)
+	for _, events := range [][]string{o.events[:18], o.events[18:]} {
+		for i, event := range events {
+			if i < 6 {
+				assert.Equal(t, "queued", event)
+			} else {
+				assert.NotEqual(t, "queued", event)
+			}
+		}
+	}
+	// The same matches played twice on the same map have different IDs
+	assert.Len(t, o.ids, 12)
+}